@@ -0,0 +1,235 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	backendBadger = "badger"
+	backendBolt   = "bolt"
+	backendMemory = "memory"
+)
+
+// internalPrefix starts the keys pda keeps for itself alongside a store's
+// data. Iterate hides them unless the prefix asked for is internal too.
+const internalPrefix = "\x00pda:"
+
+var (
+	errKeyNotFound   = errors.New("key not found")
+	errLocked        = errors.New("store is locked by another process")
+	errConflict      = errors.New("transaction conflict")
+	errNeedsRecovery = errors.New("store was not closed cleanly and must be opened for writing once to recover")
+	errReadOnly      = errors.New("refusing to write with --read-only")
+)
+
+// Entry is a single key and value along with the metadata pda keeps for it.
+type Entry struct {
+	Key       []byte `json:"key"`
+	Value     []byte `json:"value,omitempty"`
+	Meta      byte   `json:"meta,omitempty"`
+	ExpiresAt uint64 `json:"expires_at,omitempty"`
+	Version   uint64 `json:"version,omitempty"`
+}
+
+// IterOptions controls which entries Tx.Iterate visits.
+type IterOptions struct {
+	Prefix []byte
+	Values bool
+}
+
+// Backend is the storage engine behind a store. Opening one returns errLocked
+// while another process holds it.
+type Backend interface {
+	NewTx(update bool) (Tx, error)
+	NewBatch() Batch
+	Sync() error
+	Close() error
+}
+
+// Tx is a transaction against a Backend. Get returns errKeyNotFound for
+// missing or expired keys, Iterate visits keys in byte order, and Commit
+// returns errConflict if a concurrent transaction got there first.
+type Tx interface {
+	Get(key []byte) (Entry, error)
+	Set(e Entry) error
+	Delete(key []byte) error
+	Iterate(opts IterOptions, fn func(e Entry) error) error
+	Commit() error
+	Discard()
+}
+
+// Revision is one version of a key. Time is zero for versions written
+// before the store started keeping history.
+type Revision struct {
+	Entry
+	Time    time.Time
+	Deleted bool
+}
+
+// Batch buffers writes that do not need to be atomic, such as a restore.
+type Batch interface {
+	Set(e Entry) error
+	Flush() error
+	Cancel()
+}
+
+// collector is implemented by backends that can reclaim the space left by
+// overwritten, deleted and expired entries. flatten asks for a full
+// compaction on top. Collect returns the number of bytes freed on disk.
+type collector interface {
+	Collect(flatten bool) (int64, error)
+}
+
+// historian is implemented by backends that keep old versions of keys.
+// History returns the versions of key newest first.
+type historian interface {
+	History(key []byte) ([]Revision, error)
+}
+
+// DiskUsage is how much disk a store takes. LSM, VLog and Tables are only
+// known for badger stores.
+type DiskUsage struct {
+	Total  int64
+	LSM    int64
+	VLog   int64
+	Tables int
+}
+
+// sizer is implemented by backends that can report their size on disk.
+type sizer interface {
+	DiskUsage() (DiskUsage, error)
+}
+
+// snapshotter is implemented by backends that can stream their contents,
+// history included, in badger's backup format. Backup writes the versions
+// from since onwards and returns the last one written. Load adds a stream to
+// the store; Replace drops everything first.
+type snapshotter interface {
+	Backup(w io.Writer, since uint64) (uint64, error)
+	Load(r io.Reader) error
+	Replace(r io.Reader) error
+}
+
+// watcher is implemented by backends that can block until a key is written.
+// Wait returns after a write to any key starting with prefix, or once
+// timeout passes. It only sees writes made through the same open store.
+type watcher interface {
+	Wait(prefix []byte, timeout time.Duration) error
+}
+
+// capability returns db, or the backend it wraps, as a T. It is how commands
+// reach features that only some backends have.
+func capability[T any](db Backend) (T, bool) {
+	for {
+		if c, ok := db.(T); ok {
+			return c, true
+		}
+		w, ok := db.(interface{ Unwrap() Backend })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		db = w.Unwrap()
+	}
+}
+
+func internalKey(key []byte) bool {
+	return bytes.HasPrefix(key, []byte(internalPrefix))
+}
+
+// hiddenKey reports whether Iterate should skip key when asked for prefix.
+func hiddenKey(key, prefix []byte) bool {
+	return internalKey(key) && !internalKey(prefix)
+}
+
+// openBackend opens the store at path. key is the store's encryption key and
+// is only needed when meta marks the store as encrypted. A read-only store
+// takes a shared lock, so other readers can open it at the same time. On
+// both badger and bolt that shared lock still keeps writers out until the
+// readers close; only the daemon lets readers and a writer share a store.
+func openBackend(path string, meta storeMeta, key []byte, readOnly bool) (Backend, error) {
+	switch meta.Backend {
+	case "", backendBadger:
+		return openBadger(path, meta, key, readOnly)
+	case backendBolt:
+		if meta.Encrypted {
+			return nil, fmt.Errorf("the %s backend does not support encryption", backendBolt)
+		}
+		return openBolt(path, readOnly)
+	case backendMemory:
+		return openBadgerMemory()
+	default:
+		return nil, fmt.Errorf("unknown backend %q", meta.Backend)
+	}
+}
+
+// waitOpen calls openBackend until the store is no longer locked by another
+// process or timeout passes.
+func waitOpen(name, path string, meta storeMeta, key []byte, readOnly bool, timeout time.Duration) (Backend, error) {
+	deadline := time.Now().Add(timeout)
+	wait := 10 * time.Millisecond
+	for {
+		db, err := openBackend(path, meta, key, readOnly)
+		if !errors.Is(err, errLocked) {
+			return db, err
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, errLockTimeout{db: name, timeout: timeout}
+		}
+		time.Sleep(min(wait, remaining))
+		wait = min(wait*2, 250*time.Millisecond)
+	}
+}
+
+// readOnlyBackend refuses writes. It wraps every store opened under
+// --read-only, including stores held open by the daemon.
+type readOnlyBackend struct {
+	Backend
+}
+
+func (b readOnlyBackend) NewTx(update bool) (Tx, error) {
+	if update {
+		return nil, errReadOnly
+	}
+	return b.Backend.NewTx(false)
+}
+
+func (b readOnlyBackend) NewBatch() Batch {
+	return readOnlyBatch{}
+}
+
+func (b readOnlyBackend) Unwrap() Backend {
+	return b.Backend
+}
+
+type readOnlyBatch struct{}
+
+func (readOnlyBatch) Set(e Entry) error { return errReadOnly }
+func (readOnlyBatch) Flush() error      { return errReadOnly }
+func (readOnlyBatch) Cancel()           {}
@@ -0,0 +1,410 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	endian "encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/pb"
+)
+
+// timePrefix keys record when each version of a key was written, for stores
+// that keep history. A key and its time are written in one transaction, so
+// they share a version.
+const timePrefix = internalPrefix + "time:"
+
+// badgerBackend stores keys in badger. When stamp is set every write also
+// records its time under timePrefix.
+type badgerBackend struct {
+	db    *badger.DB
+	stamp bool
+}
+
+func openBadger(path string, meta storeMeta, key []byte, readOnly bool) (*badgerBackend, error) {
+	opts := badger.DefaultOptions(path).
+		WithLoggingLevel(badger.ERROR).
+		WithReadOnly(readOnly).
+		WithNumVersionsToKeep(max(meta.Versions, 1))
+	if key != nil {
+		opts = opts.
+			WithEncryptionKey(key).
+			WithIndexCacheSize(100 << 20)
+	}
+	db, err := badger.Open(opts)
+	if errors.Is(err, badger.ErrEncryptionKeyMismatch) {
+		return nil, errWrongKey
+	}
+	if errors.Is(err, badger.ErrTruncateNeeded) {
+		return nil, errNeedsRecovery
+	}
+	if err != nil && strings.Contains(err.Error(), "Cannot acquire directory lock") {
+		return nil, errLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &badgerBackend{db: db, stamp: meta.Versions > 1}, nil
+}
+
+func (b *badgerBackend) NewTx(update bool) (Tx, error) {
+	return &badgerTx{tx: b.db.NewTransaction(update), stamp: b.stamp}, nil
+}
+
+func (b *badgerBackend) NewBatch() Batch {
+	return &badgerBatch{wb: b.db.NewWriteBatch(), stamp: b.stamp}
+}
+
+// openBadgerMemory opens a store that lives only as long as the process.
+func openBadgerMemory() (*badgerBackend, error) {
+	opts := badger.DefaultOptions("").
+		WithLoggingLevel(badger.ERROR).
+		WithInMemory(true)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &badgerBackend{db: db}, nil
+}
+
+func (b *badgerBackend) Sync() error {
+	if opts := b.db.Opts(); opts.ReadOnly || opts.InMemory {
+		return nil
+	}
+	return b.db.Sync()
+}
+
+func (b *badgerBackend) Close() error {
+	return b.db.Close()
+}
+
+// Collect rewrites value log files until badger finds none worth rewriting.
+// Flattening first compacts the LSM tree so stale versions are dropped and
+// more of the value log becomes garbage.
+func (b *badgerBackend) Collect(flatten bool) (int64, error) {
+	if b.db.Opts().InMemory {
+		return 0, nil
+	}
+	before, err := b.collectableSize()
+	if err != nil {
+		return 0, err
+	}
+	if flatten {
+		if err := b.db.Flatten(runtime.NumCPU()); err != nil {
+			return 0, err
+		}
+	}
+	for {
+		err := b.db.RunValueLogGC(0.5)
+		if errors.Is(err, badger.ErrNoRewrite) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	after, err := b.collectableSize()
+	if err != nil {
+		return 0, err
+	}
+	return max(before-after, 0), nil
+}
+
+// DiskUsage uses badger's own accounting, which it refreshes every minute.
+func (b *badgerBackend) DiskUsage() (DiskUsage, error) {
+	lsm, vlog := b.db.Size()
+	return DiskUsage{Total: lsm + vlog, LSM: lsm, VLog: vlog, Tables: len(b.db.Tables())}, nil
+}
+
+// History walks every version badger still holds for key. A version's time
+// is taken from the oldest time record at or after it, since a write batch
+// may commit a key and its time separately.
+func (b *badgerBackend) History(key []byte) ([]Revision, error) {
+	var revs []Revision
+	err := b.db.View(func(tx *badger.Txn) error {
+		times, err := versionTimes(tx, timeKey(key))
+		if err != nil {
+			return err
+		}
+		return eachVersion(tx, key, func(item *badger.Item) error {
+			rev := Revision{Entry: badgerEntry(item, nil)}
+			if item.IsDeletedOrExpired() && !expired(item.ExpiresAt()) {
+				rev.Deleted = true
+			} else {
+				v, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				rev.Value = v
+			}
+			for _, t := range times {
+				if t.version >= rev.Version {
+					rev.Time = t.time
+				}
+			}
+			revs = append(revs, rev)
+			return nil
+		})
+	})
+	return revs, err
+}
+
+type versionTime struct {
+	version uint64
+	time    time.Time
+}
+
+// versionTimes reads the time records of a key, newest first.
+func versionTimes(tx *badger.Txn, key []byte) ([]versionTime, error) {
+	var times []versionTime
+	err := eachVersion(tx, key, func(item *badger.Item) error {
+		return item.Value(func(v []byte) error {
+			if len(v) == 8 {
+				nanos := int64(endian.BigEndian.Uint64(v))
+				times = append(times, versionTime{version: item.Version(), time: time.Unix(0, nanos)})
+			}
+			return nil
+		})
+	})
+	return times, err
+}
+
+// eachVersion calls fn for every version of key, newest first, including
+// deletions.
+func eachVersion(tx *badger.Txn, key []byte, fn func(item *badger.Item) error) error {
+	opts := badger.DefaultIteratorOptions
+	opts.AllVersions = true
+	opts.Prefix = key
+	it := tx.NewIterator(opts)
+	defer it.Close()
+	for it.Seek(key); it.Valid() && bytes.Equal(it.Item().Key(), key); it.Next() {
+		if err := fn(it.Item()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func timeKey(key []byte) []byte {
+	return append([]byte(timePrefix), key...)
+}
+
+// timeEntry records that key was written now. It expires with the key.
+func timeEntry(key []byte, expiresAt uint64) *badger.Entry {
+	v := make([]byte, 8)
+	endian.BigEndian.PutUint64(v, uint64(time.Now().UnixNano()))
+	entry := badger.NewEntry(timeKey(key), v)
+	entry.ExpiresAt = expiresAt
+	return entry
+}
+
+// Backup writes every version newer than since as a badger backup stream
+// and returns the version to pass as since next time. Badger decrypts an
+// encrypted store as it writes, so the stream is always plaintext.
+func (b *badgerBackend) Backup(w io.Writer, since uint64) (uint64, error) {
+	return b.db.Backup(w, since)
+}
+
+// Load writes a backup stream into the store, keeping the versions it holds.
+func (b *badgerBackend) Load(r io.Reader) error {
+	return b.db.Load(r, 256)
+}
+
+// Replace drops everything in the store before loading r. Load keeps the
+// versions in the backup, so anything left behind could shadow it.
+func (b *badgerBackend) Replace(r io.Reader) error {
+	if err := b.db.DropAll(); err != nil {
+		return err
+	}
+	return b.Load(r)
+}
+
+// errWoken ends a Subscribe once a write has been seen.
+var errWoken = errors.New("woken")
+
+func (b *badgerBackend) Wait(prefix []byte, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := b.db.Subscribe(ctx, func(*badger.KVList) error {
+		return errWoken
+	}, []pb.Match{{Prefix: prefix}})
+	if errors.Is(err, errWoken) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	return err
+}
+
+// collectableSize is the size of the files Collect can shrink: the tables
+// and every value log but the newest, which badger preallocates while it is
+// being written.
+func (b *badgerBackend) collectableSize() (int64, error) {
+	entries, err := os.ReadDir(b.db.Opts().Dir)
+	if err != nil {
+		return 0, err
+	}
+	var size, newestLog int64
+	for _, e := range entries {
+		switch filepath.Ext(e.Name()) {
+		case ".sst", ".vlog":
+		default:
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return 0, err
+		}
+		size += info.Size()
+		if filepath.Ext(e.Name()) == ".vlog" {
+			newestLog = info.Size()
+		}
+	}
+	return size - newestLog, nil
+}
+
+type badgerTx struct {
+	tx    *badger.Txn
+	stamp bool
+}
+
+func (t *badgerTx) Get(key []byte) (Entry, error) {
+	item, err := t.tx.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return Entry{}, errKeyNotFound
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	v, err := item.ValueCopy(nil)
+	if err != nil {
+		return Entry{}, err
+	}
+	return badgerEntry(item, v), nil
+}
+
+func (t *badgerTx) Set(e Entry) error {
+	if err := t.tx.SetEntry(newBadgerEntry(e)); err != nil {
+		return err
+	}
+	if t.stamp && !internalKey(e.Key) {
+		return t.tx.SetEntry(timeEntry(e.Key, e.ExpiresAt))
+	}
+	return nil
+}
+
+func (t *badgerTx) Delete(key []byte) error {
+	if err := t.tx.Delete(key); err != nil {
+		return err
+	}
+	if t.stamp && !internalKey(key) {
+		return t.tx.SetEntry(timeEntry(key, 0))
+	}
+	return nil
+}
+
+func (t *badgerTx) Iterate(opts IterOptions, fn func(e Entry) error) error {
+	iopts := badger.DefaultIteratorOptions
+	iopts.Prefix = opts.Prefix
+	iopts.PrefetchValues = opts.Values
+	it := t.tx.NewIterator(iopts)
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		if hiddenKey(item.Key(), opts.Prefix) {
+			continue
+		}
+		var v []byte
+		if opts.Values {
+			var err error
+			v, err = item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+		}
+		if err := fn(badgerEntry(item, v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *badgerTx) Commit() error {
+	err := t.tx.Commit()
+	if errors.Is(err, badger.ErrConflict) {
+		return errConflict
+	}
+	return err
+}
+
+func (t *badgerTx) Discard() {
+	t.tx.Discard()
+}
+
+type badgerBatch struct {
+	wb    *badger.WriteBatch
+	stamp bool
+}
+
+func (b *badgerBatch) Set(e Entry) error {
+	if err := b.wb.SetEntry(newBadgerEntry(e)); err != nil {
+		return err
+	}
+	if b.stamp && !internalKey(e.Key) {
+		return b.wb.SetEntry(timeEntry(e.Key, e.ExpiresAt))
+	}
+	return nil
+}
+
+func (b *badgerBatch) Flush() error {
+	return b.wb.Flush()
+}
+
+func (b *badgerBatch) Cancel() {
+	b.wb.Cancel()
+}
+
+func badgerEntry(item *badger.Item, v []byte) Entry {
+	return Entry{
+		Key:       item.KeyCopy(nil),
+		Value:     v,
+		Meta:      item.UserMeta(),
+		ExpiresAt: item.ExpiresAt(),
+		Version:   item.Version(),
+	}
+}
+
+func newBadgerEntry(e Entry) *badger.Entry {
+	entry := badger.NewEntry(e.Key, e.Value).WithMeta(e.Meta)
+	entry.ExpiresAt = e.ExpiresAt
+	return entry
+}
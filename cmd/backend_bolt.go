@@ -0,0 +1,249 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	endian "encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
+)
+
+const (
+	boltFile   = "bolt.db"
+	boltHeader = 17
+)
+
+var boltBucket = []byte("pda")
+
+// boltBackend keeps a whole store in a single bbolt file. Each value is
+// prefixed with its meta byte, expiry and version.
+type boltBackend struct {
+	db *bolt.DB
+}
+
+func openBolt(path string, readOnly bool) (*boltBackend, error) {
+	db, err := bolt.Open(filepath.Join(path, boltFile), 0o600, &bolt.Options{Timeout: 10 * time.Millisecond, ReadOnly: readOnly})
+	if errors.Is(err, bolterrors.ErrTimeout) {
+		return nil, errLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	if readOnly {
+		return &boltBackend{db: db}, nil
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltBackend{db: db}, nil
+}
+
+func (b *boltBackend) NewTx(update bool) (Tx, error) {
+	tx, err := b.db.Begin(update)
+	if err != nil {
+		return nil, err
+	}
+	bucket := tx.Bucket(boltBucket)
+	if bucket == nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("%s has no %q bucket", b.db.Path(), boltBucket)
+	}
+	return &boltTx{tx: tx, bucket: bucket}, nil
+}
+
+func (b *boltBackend) NewBatch() Batch {
+	return &boltBatch{db: b.db}
+}
+
+func (b *boltBackend) Sync() error {
+	return nil
+}
+
+func (b *boltBackend) Close() error {
+	return b.db.Close()
+}
+
+func (b *boltBackend) DiskUsage() (DiskUsage, error) {
+	info, err := os.Stat(b.db.Path())
+	if err != nil {
+		return DiskUsage{}, err
+	}
+	return DiskUsage{Total: info.Size()}, nil
+}
+
+// Collect deletes expired entries, which bolt otherwise only hides. bbolt
+// reuses freed pages but never shrinks its file, so nothing is freed on disk
+// and there is nothing more for flatten to do.
+func (b *boltBackend) Collect(flatten bool) (int64, error) {
+	return 0, b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		var stale [][]byte
+		err := bucket.ForEach(func(k, raw []byte) error {
+			e, err := decodeBoltEntry(k, raw, false)
+			if err != nil {
+				return err
+			}
+			if expired(e.ExpiresAt) {
+				stale = append(stale, e.Key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type boltTx struct {
+	tx     *bolt.Tx
+	bucket *bolt.Bucket
+}
+
+func (t *boltTx) Get(key []byte) (Entry, error) {
+	raw := t.bucket.Get(key)
+	if raw == nil {
+		return Entry{}, errKeyNotFound
+	}
+	e, err := decodeBoltEntry(key, raw, true)
+	if err != nil {
+		return Entry{}, err
+	}
+	if expired(e.ExpiresAt) {
+		return Entry{}, errKeyNotFound
+	}
+	return e, nil
+}
+
+func (t *boltTx) Set(e Entry) error {
+	return putBolt(t.bucket, e)
+}
+
+func (t *boltTx) Delete(key []byte) error {
+	return t.bucket.Delete(key)
+}
+
+func (t *boltTx) Iterate(opts IterOptions, fn func(e Entry) error) error {
+	c := t.bucket.Cursor()
+	for k, raw := c.Seek(opts.Prefix); k != nil && bytes.HasPrefix(k, opts.Prefix); k, raw = c.Next() {
+		if hiddenKey(k, opts.Prefix) {
+			continue
+		}
+		e, err := decodeBoltEntry(k, raw, opts.Values)
+		if err != nil {
+			return err
+		}
+		if expired(e.ExpiresAt) {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *boltTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *boltTx) Discard() {
+	_ = t.tx.Rollback()
+}
+
+type boltBatch struct {
+	db      *bolt.DB
+	pending []Entry
+}
+
+func (b *boltBatch) Set(e Entry) error {
+	b.pending = append(b.pending, e)
+	return nil
+}
+
+func (b *boltBatch) Flush() error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, e := range b.pending {
+			if err := putBolt(bucket, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	b.pending = nil
+	return err
+}
+
+func (b *boltBatch) Cancel() {
+	b.pending = nil
+}
+
+func putBolt(bucket *bolt.Bucket, e Entry) error {
+	version, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	raw := make([]byte, boltHeader+len(e.Value))
+	raw[0] = e.Meta
+	endian.BigEndian.PutUint64(raw[1:9], e.ExpiresAt)
+	endian.BigEndian.PutUint64(raw[9:17], version)
+	copy(raw[boltHeader:], e.Value)
+	return bucket.Put(e.Key, raw)
+}
+
+func decodeBoltEntry(key, raw []byte, values bool) (Entry, error) {
+	if len(raw) < boltHeader {
+		return Entry{}, fmt.Errorf("corrupt entry for %q", key)
+	}
+	e := Entry{
+		Key:       bytes.Clone(key),
+		Meta:      raw[0],
+		ExpiresAt: endian.BigEndian.Uint64(raw[1:9]),
+		Version:   endian.BigEndian.Uint64(raw[9:17]),
+	}
+	if values {
+		e.Value = bytes.Clone(raw[boltHeader:])
+	}
+	return e, nil
+}
+
+func expired(expiresAt uint64) bool {
+	return expiresAt != 0 && expiresAt <= uint64(time.Now().Unix())
+}
@@ -0,0 +1,304 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const envNoDaemon = "PDA_NO_DAEMON"
+
+// DaemonArgs carries the arguments of every daemon RPC; each method reads
+// only the fields it needs.
+type DaemonArgs struct {
+	Name     string
+	Path     string
+	Meta     storeMeta
+	StoreKey []byte
+	Timeout  time.Duration
+	Handle   uint64
+	Tx       uint64
+	Update   bool
+	Flatten  bool
+	Key      []byte
+	Entry    Entry
+	Entries  []Entry
+	Iter     IterOptions
+	Data     []byte
+	Since    uint64
+}
+
+// DaemonBackup is the reply to a Backup RPC.
+type DaemonBackup struct {
+	Data    []byte
+	Version uint64
+}
+
+// daemonSocket returns the unix socket the daemon listens on, in
+// $XDG_RUNTIME_DIR or a per-user directory under the system temp dir.
+func daemonSocket() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("pda-%d", os.Getuid()))
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "pda.sock"), nil
+}
+
+// dialDaemon connects to a running daemon, or returns nil if there is none
+// or PDA_NO_DAEMON is set.
+func dialDaemon() *rpc.Client {
+	if os.Getenv(envNoDaemon) != "" {
+		return nil
+	}
+	socket, err := daemonSocket()
+	if err != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", socket, 100*time.Millisecond)
+	if err != nil {
+		return nil
+	}
+	return rpc.NewClient(conn)
+}
+
+// remoteBackend forwards every operation to a store held open by the daemon.
+type remoteBackend struct {
+	client *rpc.Client
+	handle uint64
+}
+
+func openRemote(client *rpc.Client, name, path string, meta storeMeta, key []byte, timeout time.Duration) (Backend, error) {
+	var handle uint64
+	args := DaemonArgs{Name: name, Path: path, Meta: meta, StoreKey: key, Timeout: timeout}
+	if err := client.Call("Daemon.Open", args, &handle); err != nil {
+		client.Close()
+		return nil, remoteError(err)
+	}
+	return &remoteBackend{client: client, handle: handle}, nil
+}
+
+func (b *remoteBackend) call(method string, args DaemonArgs, reply any) error {
+	args.Handle = b.handle
+	return remoteError(b.client.Call("Daemon."+method, args, reply))
+}
+
+func (b *remoteBackend) NewTx(update bool) (Tx, error) {
+	var id uint64
+	if err := b.call("Begin", DaemonArgs{Update: update}, &id); err != nil {
+		return nil, err
+	}
+	return &remoteTx{b: b, id: id}, nil
+}
+
+func (b *remoteBackend) NewBatch() Batch {
+	return &remoteBatch{b: b}
+}
+
+func (b *remoteBackend) Sync() error {
+	var ok bool
+	return b.call("Sync", DaemonArgs{}, &ok)
+}
+
+func (b *remoteBackend) Collect(flatten bool) (int64, error) {
+	var freed int64
+	err := b.call("Collect", DaemonArgs{Flatten: flatten}, &freed)
+	return freed, err
+}
+
+func (b *remoteBackend) DiskUsage() (DiskUsage, error) {
+	var usage DiskUsage
+	err := b.call("DiskUsage", DaemonArgs{}, &usage)
+	return usage, err
+}
+
+func (b *remoteBackend) History(key []byte) ([]Revision, error) {
+	var revs []Revision
+	err := b.call("History", DaemonArgs{Key: key}, &revs)
+	return revs, err
+}
+
+// Backup, Load and Replace pass the whole stream in one message, which gob
+// caps at 1GB.
+func (b *remoteBackend) Backup(w io.Writer, since uint64) (uint64, error) {
+	var backup DaemonBackup
+	if err := b.call("Backup", DaemonArgs{Since: since}, &backup); err != nil {
+		return 0, err
+	}
+	_, err := w.Write(backup.Data)
+	return backup.Version, err
+}
+
+func (b *remoteBackend) Load(r io.Reader) error {
+	return b.send("Load", r)
+}
+
+func (b *remoteBackend) Replace(r io.Reader) error {
+	return b.send("Replace", r)
+}
+
+func (b *remoteBackend) send(method string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var ok bool
+	return b.call(method, DaemonArgs{Data: data}, &ok)
+}
+
+func (b *remoteBackend) Wait(prefix []byte, timeout time.Duration) error {
+	var ok bool
+	return b.call("Wait", DaemonArgs{Key: prefix, Timeout: timeout}, &ok)
+}
+
+func (b *remoteBackend) Close() error {
+	var ok bool
+	err := b.call("Close", DaemonArgs{}, &ok)
+	if cerr := b.client.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type remoteTx struct {
+	b  *remoteBackend
+	id uint64
+}
+
+func (t *remoteTx) Get(key []byte) (Entry, error) {
+	var e Entry
+	err := t.b.call("Get", DaemonArgs{Tx: t.id, Key: key}, &e)
+	return e, err
+}
+
+func (t *remoteTx) Set(e Entry) error {
+	var ok bool
+	return t.b.call("Set", DaemonArgs{Tx: t.id, Entry: e}, &ok)
+}
+
+func (t *remoteTx) Delete(key []byte) error {
+	var ok bool
+	return t.b.call("Delete", DaemonArgs{Tx: t.id, Key: key}, &ok)
+}
+
+func (t *remoteTx) Iterate(opts IterOptions, fn func(e Entry) error) error {
+	var entries []Entry
+	if err := t.b.call("Iterate", DaemonArgs{Tx: t.id, Iter: opts}, &entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *remoteTx) Commit() error {
+	var ok bool
+	return t.b.call("Commit", DaemonArgs{Tx: t.id}, &ok)
+}
+
+func (t *remoteTx) Discard() {
+	var ok bool
+	_ = t.b.call("Discard", DaemonArgs{Tx: t.id}, &ok)
+}
+
+type remoteBatch struct {
+	b       *remoteBackend
+	pending []Entry
+}
+
+func (b *remoteBatch) Set(e Entry) error {
+	b.pending = append(b.pending, e)
+	return nil
+}
+
+func (b *remoteBatch) Flush() error {
+	var ok bool
+	err := b.b.call("Flush", DaemonArgs{Entries: b.pending}, &ok)
+	b.pending = nil
+	return err
+}
+
+func (b *remoteBatch) Cancel() {
+	b.pending = nil
+}
+
+// releaseStore asks the active pool or a running daemon to close the store
+// at path so that it can be deleted or rewritten on disk.
+func releaseStore(path string) error {
+	if activePool != nil {
+		return activePool.evict(path)
+	}
+	client := dialDaemon()
+	if client == nil {
+		return nil
+	}
+	defer client.Close()
+	var ok bool
+	return remoteError(client.Call("Daemon.Release", DaemonArgs{Path: path}, &ok))
+}
+
+// remoteSentinels are the errors remoteError restores. Some arrive wrapped
+// with detail, as in "wrong kind of value: ...".
+var remoteSentinels = []error{
+	errKeyNotFound, errConflict, errLocked, errWrongKey,
+	errWrongKind, errReadOnly, errNeedsRecovery,
+}
+
+var lockTimeoutMessage = regexp.MustCompile(`^timed out after (\S+) waiting for another pda process to release @(.*?); `)
+
+// remoteError restores the errors that callers compare against, which
+// net/rpc flattens into strings.
+func remoteError(err error) error {
+	var serverErr rpc.ServerError
+	if !errors.As(err, &serverErr) {
+		return err
+	}
+	msg := string(serverErr)
+	for _, known := range remoteSentinels {
+		if msg == known.Error() {
+			return known
+		}
+		if detail, ok := strings.CutPrefix(msg, known.Error()+":"); ok {
+			return fmt.Errorf("%w:%s", known, detail)
+		}
+	}
+	if m := lockTimeoutMessage.FindStringSubmatch(msg); m != nil {
+		if timeout, err := time.ParseDuration(m[1]); err == nil {
+			return errLockTimeout{db: m[2], timeout: timeout}
+		}
+	}
+	return errors.New(msg)
+}
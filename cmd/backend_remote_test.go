@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// dialTestDaemon serves a daemon over an in-process pipe and returns a
+// client for it.
+func dialTestDaemon(t *testing.T) *rpc.Client {
+	t.Helper()
+	server, client := net.Pipe()
+	d := &daemon{pool: newStorePool(time.Minute)}
+	go d.serve(server)
+	t.Cleanup(d.pool.closeAll)
+	return rpc.NewClient(client)
+}
+
+func TestRemoteErrorRestoresTypedErrors(t *testing.T) {
+	timeout := errLockTimeout{db: "vault", timeout: 1500 * time.Millisecond}
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{errKeyNotFound, errKeyNotFound},
+		{errConflict, errConflict},
+		{errLocked, errLocked},
+		{errWrongKey, errWrongKey},
+		{errReadOnly, errReadOnly},
+		{errNeedsRecovery, errNeedsRecovery},
+		{fmt.Errorf("%w: %q holds a list, not a hash", errWrongKind, "k"), errWrongKind},
+	}
+	for _, tt := range tests {
+		got := remoteError(rpc.ServerError(tt.err.Error()))
+		if !errors.Is(got, tt.want) {
+			t.Errorf("remoteError(%q) = %v, want errors.Is %v", tt.err, got, tt.want)
+		}
+		if got.Error() != tt.err.Error() {
+			t.Errorf("remoteError(%q) reads %q", tt.err, got)
+		}
+	}
+	var lockErr errLockTimeout
+	if got := remoteError(rpc.ServerError(timeout.Error())); !errors.As(got, &lockErr) || lockErr != timeout {
+		t.Errorf("remoteError(%q) = %#v, want %#v", timeout, got, timeout)
+	}
+	if got := remoteError(rpc.ServerError("something else")); got.Error() != "something else" {
+		t.Errorf("remoteError kept %q as %q", "something else", got)
+	}
+}
+
+func TestRemoteBackendErrors(t *testing.T) {
+	client := dialTestDaemon(t)
+	db, err := openRemote(client, ":mem:t", ":mem:t", storeMeta{Backend: backendMemory}, nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, err := db.NewTx(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Discard()
+	if _, err := tx.Get([]byte("missing")); !errors.Is(err, errKeyNotFound) {
+		t.Errorf("remote Get of a missing key = %v, want errKeyNotFound", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	dir := filepath.Join(t.TempDir(), "locked")
+	store, err := openBadger(dir, storeMeta{}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	unlock, err := lockStoreDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	_, err = openRemote(dialTestDaemon(t), "locked", dir, storeMeta{}, nil, 50*time.Millisecond)
+	if !errors.As(err, new(errLockTimeout)) {
+		t.Errorf("remote open of a locked store = %v, want errLockTimeout", err)
+	}
+}
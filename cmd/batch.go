@@ -0,0 +1,313 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// batchCmd represents the batch command
+var batchCmd = &cobra.Command{
+	Use:   "batch [DB]",
+	Short: "Apply a script of sets and deletes to a db.",
+	Long: `Apply a script of sets and deletes to a db, read from stdin or --file.
+
+Each line is one operation; blank lines and lines starting with # are
+skipped, and words are quoted as in a shell:
+
+  set KEY VALUE [--ttl DURATION] [--secret] [--type TYPE]
+      [--if-absent | --if-exists | --if-value OLD | --if-version N]
+  del KEY
+  expire KEY DURATION      (0 removes the expiry)
+
+A set with an --if flag only happens if the key is absent, exists, holds
+OLD, or is at version N, as for pda set. Keys may name the db with KEY@DB, but only the db being batched.
+
+With --atomic the whole script is one transaction: it all happens or, if any
+line fails, none of it does. Otherwise every line is applied on its own, in
+order, and its result is printed; the batch fails if any line did.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: writesData,
+	RunE:        batch,
+}
+
+// batchOp is one parsed line of a batch script.
+type batchOp struct {
+	line   int
+	text   string
+	op     string
+	key    []byte
+	value  []byte
+	ttl    time.Duration
+	hasTTL bool
+	secret bool
+	vt     valueType
+	typed  bool
+	cond   setCondition
+}
+
+func batch(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	dbName := defaultDB()
+	if len(args) == 1 {
+		parsed, err := store.parseDB(args[0], false)
+		if err != nil {
+			return err
+		}
+		dbName = parsed
+	}
+	atomic, err := cmd.Flags().GetBool("atomic")
+	if err != nil {
+		return err
+	}
+	reader, closer, err := restoreInput(cmd)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	defaultTTL, err := configValue("set.ttl")
+	if err != nil {
+		return err
+	}
+	ttl, err := time.ParseDuration(defaultTTL)
+	if err != nil {
+		return fmt.Errorf("set.ttl: %w", err)
+	}
+
+	var ops []batchOp
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 1024*1024), 8*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		op, err := parseBatchOp(store, dbName, text)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		op.line = lineNo
+		if op.op == "set" && !op.hasTTL {
+			op.ttl = ttl
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	db, err := store.open(dbName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	keys := newKeyring()
+	var deleted int
+	if atomic {
+		trans := TransactionArgs{
+			readonly: false,
+			sync:     false,
+			transact: func(tx Tx, k []byte) error {
+				deleted = 0
+				for _, op := range ops {
+					if err := op.apply(tx, keys); err != nil {
+						return fmt.Errorf("line %d: %w", op.line, err)
+					}
+					if op.op == "del" {
+						deleted++
+					}
+				}
+				return nil
+			},
+		}
+		if err := store.retry(db, trans, nil); err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%w; nothing was applied", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Applied %d operations to @%s\n", len(ops), dbName)
+		return autoGC(cmd, db, dbName, deleted)
+	}
+
+	var failed int
+	for _, op := range ops {
+		trans := TransactionArgs{
+			readonly: false,
+			sync:     false,
+			transact: func(tx Tx, k []byte) error {
+				return op.apply(tx, keys)
+			},
+		}
+		err := store.retry(db, trans, nil)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(cmd.OutOrStdout(), "%d failed: %s: %v\n", op.line, op.text, err)
+		default:
+			if op.op == "del" {
+				deleted++
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%d ok\n", op.line)
+		}
+	}
+	if err := autoGC(cmd, db, dbName, deleted); err != nil {
+		return err
+	}
+	if failed > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d of %d operations failed", failed, len(ops))
+	}
+	return nil
+}
+
+// parseBatchOp parses one line of a batch script against dbName.
+func parseBatchOp(store *Store, dbName, text string) (batchOp, error) {
+	words, err := splitWords(text)
+	if err != nil {
+		return batchOp{}, err
+	}
+	op := batchOp{text: text, op: strings.ToLower(words[0])}
+	fs := pflag.NewFlagSet(op.op, pflag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	var want int
+	switch op.op {
+	case "set":
+		want = 2
+		fs.DurationVar(&op.ttl, "ttl", 0, "")
+		fs.BoolVar(&op.secret, "secret", false, "")
+		fs.Func("type", "", func(v string) error {
+			var err error
+			op.vt, err = parseValueType(v)
+			op.typed = true
+			return err
+		})
+		fs.BoolVar(&op.cond.absent, "if-absent", false, "")
+		fs.BoolVar(&op.cond.exists, "if-exists", false, "")
+		fs.Func("if-value", "", func(v string) error {
+			op.cond.value = &v
+			return nil
+		})
+		fs.Func("if-version", "", func(v string) error {
+			n, err := strconv.ParseUint(v, 10, 64)
+			op.cond.version = &n
+			return err
+		})
+	case "del":
+		want = 1
+	case "expire":
+		want = 2
+	default:
+		return op, fmt.Errorf("unknown operation %q; use set, del or expire", words[0])
+	}
+	if err := fs.Parse(words[1:]); err != nil {
+		return op, err
+	}
+	rest := fs.Args()
+	if len(rest) != want {
+		return op, fmt.Errorf("%s takes %d arguments, got %d", op.op, want, len(rest))
+	}
+	if err := op.cond.validate(); err != nil {
+		return op, err
+	}
+	op.hasTTL = fs.Changed("ttl")
+
+	key, db, err := store.parse(rest[0], false)
+	if err != nil {
+		return op, err
+	}
+	if db != "" && db != dbName {
+		return op, fmt.Errorf("%q is not in @%s", rest[0], dbName)
+	}
+	op.key = key
+	switch op.op {
+	case "set":
+		op.value = []byte(rest[1])
+	case "expire":
+		if op.ttl, err = time.ParseDuration(rest[1]); err != nil {
+			return op, err
+		}
+	}
+	return op, nil
+}
+
+func (op batchOp) apply(tx Tx, keys *keyring) error {
+	switch op.op {
+	case "del":
+		return tx.Delete(op.key)
+	case "expire":
+		e, err := tx.Get(op.key)
+		if err != nil {
+			return err
+		}
+		e.Version = 0
+		e.ExpiresAt = 0
+		if op.ttl > 0 {
+			e.ExpiresAt = uint64(time.Now().Add(op.ttl).Unix())
+		}
+		return tx.Set(e)
+	}
+	if err := op.cond.check(tx, op.key, keys); err != nil {
+		return err
+	}
+	_, err := tx.Get(op.key)
+	created := errors.Is(err, errKeyNotFound)
+	if err != nil && !created {
+		return err
+	}
+	typeMeta, err := typedMeta(tx, op.key, op.value, op.vt, op.typed)
+	if err != nil {
+		return err
+	}
+	entry := Entry{Key: op.key, Value: op.value, Meta: typeMeta}
+	if op.secret {
+		sealed, err := keys.seal(op.value)
+		if err != nil {
+			return err
+		}
+		entry.Value = sealed
+		entry.Meta |= metaSecret | metaEncrypted
+	}
+	if op.ttl != 0 {
+		entry.ExpiresAt = uint64(time.Now().Add(op.ttl).Unix())
+	}
+	if err := tx.Set(entry); err != nil {
+		return err
+	}
+	return annotateSet(tx, op.key, created, labelChange{})
+}
+
+func init() {
+	batchCmd.Flags().StringP("file", "f", "", "Path to the script (defaults to stdin)")
+	batchCmd.Flags().Bool("atomic", false, "apply the whole script in one transaction, or none of it")
+	rootCmd.AddCommand(batchCmd)
+}
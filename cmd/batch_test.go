@@ -0,0 +1,42 @@
+package cmd
+
+import "testing"
+
+func TestBatchSetClearsLeftoverAnnotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+		want     string
+	}{
+		{"new key", false, ""},
+		{"existing key", true, "kept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := openBadgerMemory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			tx, _ := db.NewTx(true)
+			defer tx.Discard()
+			note := "kept"
+			if err := writeAnnotation(tx, []byte("k"), annotation{Note: note}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing {
+				if err := tx.Set(Entry{Key: []byte("k"), Value: []byte("old")}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			op := batchOp{op: "set", key: []byte("k"), value: []byte("v")}
+			if err := op.apply(tx, newKeyring()); err != nil {
+				t.Fatal(err)
+			}
+			a, err := readAnnotation(tx, []byte("k"))
+			if err != nil || a.Note != tt.want {
+				t.Errorf("note = %q, %v, want %q", a.Note, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	endian "encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A collection is a key whose elements are kept as sub-keys, so that
+// adding or removing one does not rewrite the rest. The key itself holds a
+// collectionHead and is marked with its kind in the metaKind bits.
+const (
+	metaKind byte = 0xc
+	kindList byte = 0x4
+	kindHash byte = 0x8
+	kindSet  byte = 0xc
+)
+
+// elemPrefix starts the sub-keys of every collection. A sub-key is the
+// parent key, a zero byte, the collection's id and then the element's own
+// name. A new id is picked whenever a collection is created, so the
+// elements of one that has been deleted or overwritten are never seen
+// again, and undoing the delete brings them back. gc drops them once
+// neither the trash nor the journal could.
+const elemPrefix = internalPrefix + "elem:"
+
+// sweepChunk bounds how many keys deleteKeys deletes in one transaction.
+const sweepChunk = 1000
+
+var errWrongKind = errors.New("wrong kind of value")
+
+// collectionHead is the value of a collection's key. Lists use head and
+// tail as the sequence numbers of their first element and one past their
+// last. Hashes and sets keep head at listStart and tail past it by their
+// size.
+type collectionHead struct {
+	id   uint64
+	head uint64
+	tail uint64
+}
+
+// listStart is where head and tail begin, so that elements can be added at
+// either end.
+const listStart = 1 << 63
+
+func newCollectionHead() collectionHead {
+	return collectionHead{id: uint64(time.Now().UnixNano()), head: listStart, tail: listStart}
+}
+
+func (c collectionHead) encode() []byte {
+	b := endian.BigEndian.AppendUint64(nil, c.id)
+	b = endian.BigEndian.AppendUint64(b, c.head)
+	return endian.BigEndian.AppendUint64(b, c.tail)
+}
+
+func decodeCollectionHead(v []byte) (collectionHead, error) {
+	if len(v) != 24 {
+		return collectionHead{}, fmt.Errorf("corrupt collection header")
+	}
+	return collectionHead{
+		id:   endian.BigEndian.Uint64(v),
+		head: endian.BigEndian.Uint64(v[8:]),
+		tail: endian.BigEndian.Uint64(v[16:]),
+	}, nil
+}
+
+// elemKey returns the sub-key holding the element called name of the
+// collection at key.
+func elemKey(key []byte, id uint64, name []byte) []byte {
+	k := append([]byte(elemPrefix), key...)
+	k = append(k, 0)
+	k = endian.BigEndian.AppendUint64(k, id)
+	return append(k, name...)
+}
+
+func kindName(meta byte) string {
+	switch meta & metaKind {
+	case kindList:
+		return "list"
+	case kindHash:
+		return "hash"
+	case kindSet:
+		return "set"
+	default:
+		return "string"
+	}
+}
+
+// checkKind returns errWrongKind, wrapped with what key holds instead,
+// unless e is of the given kind. Plain values are kind 0.
+func checkKind(key []byte, e Entry, kind byte) error {
+	if e.Meta&metaKind == kind {
+		return nil
+	}
+	return fmt.Errorf("%w: %q holds a %s, not a %s", errWrongKind, key, kindName(e.Meta), kindName(kind))
+}
+
+// readCollection returns the header of the collection of the given kind at
+// key. found is false if the key does not exist.
+func readCollection(tx Tx, key []byte, kind byte) (e Entry, c collectionHead, found bool, err error) {
+	e, err = tx.Get(key)
+	if errors.Is(err, errKeyNotFound) {
+		return Entry{Key: key, Meta: kind}, newCollectionHead(), false, nil
+	}
+	if err != nil {
+		return e, c, false, err
+	}
+	if err := checkKind(key, e, kind); err != nil {
+		return e, c, false, err
+	}
+	c, err = decodeCollectionHead(e.Value)
+	return e, c, true, err
+}
+
+// writeCollection stores c as the header of the collection e, keeping its
+// meta and expiry.
+func writeCollection(tx Tx, e Entry, c collectionHead) error {
+	e.Value = c.encode()
+	e.Version = 0
+	return tx.Set(e)
+}
+
+// describeCollection is shown in place of a collection's value.
+func describeCollection(e Entry) string {
+	c, err := decodeCollectionHead(e.Value)
+	if err != nil {
+		return "(corrupt " + kindName(e.Meta) + ")"
+	}
+	return fmt.Sprintf("(%s of %d)", kindName(e.Meta), c.tail-c.head)
+}
+
+// putElem stores value as the element name of the hash or set c at key,
+// counting it in c if it is new.
+func putElem(tx Tx, key []byte, c *collectionHead, name, value []byte) error {
+	ek := elemKey(key, c.id, name)
+	_, err := tx.Get(ek)
+	switch {
+	case errors.Is(err, errKeyNotFound):
+		c.tail++
+	case err != nil:
+		return err
+	}
+	return tx.Set(Entry{Key: ek, Value: value})
+}
+
+// deleteElem removes the element name of the hash or set c at key, if it
+// has one.
+func deleteElem(tx Tx, key []byte, c *collectionHead, name []byte) error {
+	ek := elemKey(key, c.id, name)
+	_, err := tx.Get(ek)
+	if errors.Is(err, errKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.tail--
+	return tx.Delete(ek)
+}
+
+// eachElem calls fn with the name and value of every element of the hash
+// or set c at key, in name order.
+func eachElem(tx Tx, key []byte, c collectionHead, fn func(name, value []byte) error) error {
+	prefix := elemKey(key, c.id, nil)
+	return tx.Iterate(IterOptions{Prefix: prefix, Values: true}, func(e Entry) error {
+		return fn(e.Key[len(prefix):], e.Value)
+	})
+}
+
+// holds describes the size of a collection after a change, e.g. "q" now
+// holds 1 value.
+func holds(key string, n uint64, noun string) string {
+	if n != 1 {
+		noun += "s"
+	}
+	return fmt.Sprintf("%q now holds %d %s", key, n, noun)
+}
+
+// sweepElems deletes the elements of collections that nothing can bring
+// back: their header has been deleted, overwritten or has expired, and is
+// held neither in the trash nor in the journal. It returns how many
+// elements it deleted.
+func sweepElems(db Backend) (int, error) {
+	tx, err := db.NewTx(false)
+	if err != nil {
+		return 0, err
+	}
+	live := map[string]bool{}
+	keep := func(key []byte, e Entry) {
+		if e.Meta&metaKind == 0 {
+			return
+		}
+		if c, err := decodeCollectionHead(e.Value); err == nil {
+			live[string(elemKey(key, c.id, nil))] = true
+		}
+	}
+	err = tx.Iterate(IterOptions{Values: true}, func(e Entry) error {
+		keep(e.Key, e)
+		return nil
+	})
+	if err == nil {
+		err = tx.Iterate(IterOptions{Prefix: []byte(trashPrefix), Values: true}, func(e Entry) error {
+			rec, err := decodeTrash(e)
+			if err != nil {
+				return err
+			}
+			keep(e.Key[len(trashPrefix):], rec.Entry)
+			return nil
+		})
+	}
+	var records []journalRecord
+	if err == nil {
+		_, records, err = readJournal(tx)
+	}
+	for _, rec := range records {
+		if rec.Prev != nil {
+			keep(rec.Key, *rec.Prev)
+		}
+	}
+	var orphans [][]byte
+	if err == nil {
+		err = tx.Iterate(IterOptions{Prefix: []byte(elemPrefix)}, func(e Entry) error {
+			if !liveElem(live, e.Key) {
+				orphans = append(orphans, e.Key)
+			}
+			return nil
+		})
+	}
+	tx.Discard()
+	if err != nil {
+		return 0, err
+	}
+	return len(orphans), deleteKeys(db, orphans)
+}
+
+// liveElem reports whether the element k belongs to a collection in live,
+// which holds elemKey(key, id, nil) of each. Keys may contain zero bytes,
+// so every zero byte is tried as the end of the key.
+func liveElem(live map[string]bool, k []byte) bool {
+	for i := len(elemPrefix); i+9 <= len(k); i++ {
+		if k[i] == 0 && live[string(k[:i+9])] {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteKeys deletes keys from db, sweepChunk at a time.
+func deleteKeys(db Backend, keys [][]byte) error {
+	for len(keys) > 0 {
+		chunk := keys[:min(len(keys), sweepChunk)]
+		keys = keys[len(chunk):]
+		tx, err := db.NewTx(true)
+		if err != nil {
+			return err
+		}
+		for _, k := range chunk {
+			if err := tx.Delete(k); err != nil {
+				tx.Discard()
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,543 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	gap "github.com/muesli/go-app-paths"
+	"github.com/spf13/cobra"
+)
+
+const configFile = "config.toml"
+
+// config mirrors config.toml. Every field is optional; unset fields fall
+// back to the built-in defaults.
+type config struct {
+	DB       string         `toml:"db,omitempty"`
+	List     listConfig     `toml:"list,omitempty"`
+	Delete   deleteConfig   `toml:"delete,omitempty"`
+	Set      setConfig      `toml:"set,omitempty"`
+	Lock     lockConfig     `toml:"lock,omitempty"`
+	GC       gcConfig       `toml:"gc,omitempty"`
+	Journal  journalConfig  `toml:"journal,omitempty"`
+	Trash    trashConfig    `toml:"trash,omitempty"`
+	Snapshot snapshotConfig `toml:"snapshot,omitempty"`
+}
+
+type listConfig struct {
+	Format  string `toml:"format,omitempty"`
+	Columns string `toml:"columns,omitempty"`
+}
+
+type deleteConfig struct {
+	Prompt *bool `toml:"prompt,omitempty"`
+}
+
+type setConfig struct {
+	TTL string `toml:"ttl,omitempty"`
+}
+
+type lockConfig struct {
+	Timeout string `toml:"timeout,omitempty"`
+}
+
+type gcConfig struct {
+	Auto      *bool `toml:"auto,omitempty"`
+	Threshold int   `toml:"threshold,omitempty"`
+}
+
+type journalConfig struct {
+	Size *int `toml:"size,omitempty"`
+}
+
+type trashConfig struct {
+	Retention string `toml:"retention,omitempty"`
+}
+
+type snapshotConfig struct {
+	Retention *int `toml:"retention,omitempty"`
+}
+
+// configSetting describes one key accepted by `pda config`.
+type configSetting struct {
+	key   string
+	env   string
+	def   string
+	get   func(c *config) string
+	set   func(c *config, v string) error
+	usage string
+}
+
+var configSettings = []configSetting{
+	{
+		key:   "db",
+		env:   "PDA_DB",
+		def:   "default",
+		usage: "db used when a key or command names none",
+		get:   func(c *config) string { return c.DB },
+		set: func(c *config, v string) error {
+			db := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(v), "@"))
+			if strings.Contains(db, "@") {
+				return fmt.Errorf("bad db format, use DB or @DB")
+			}
+			c.DB = db
+			return nil
+		},
+	},
+	{
+		key:   "list.format",
+		env:   "PDA_LIST_FORMAT",
+		def:   "table",
+		usage: "default list --format",
+		get:   func(c *config) string { return c.List.Format },
+		set: func(c *config, v string) error {
+			if v != "" {
+				var f formatEnum
+				if err := f.Set(v); err != nil {
+					return err
+				}
+			}
+			c.List.Format = v
+			return nil
+		},
+	},
+	{
+		key:   "list.columns",
+		env:   "PDA_LIST_COLUMNS",
+		def:   "key,value",
+		usage: "default list columns, a comma-separated subset of key,value,ttl,version,type,labels,note",
+		get:   func(c *config) string { return c.List.Columns },
+		set: func(c *config, v string) error {
+			if v != "" {
+				if _, err := parseColumnList(v); err != nil {
+					return err
+				}
+			}
+			c.List.Columns = v
+			return nil
+		},
+	},
+	{
+		key:   "delete.prompt",
+		env:   "PDA_DELETE_PROMPT",
+		def:   "true",
+		usage: "ask for confirmation before del and delete-db",
+		get: func(c *config) string {
+			if c.Delete.Prompt == nil {
+				return ""
+			}
+			return strconv.FormatBool(*c.Delete.Prompt)
+		},
+		set: func(c *config, v string) error {
+			if v == "" {
+				c.Delete.Prompt = nil
+				return nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("delete.prompt must be true or false")
+			}
+			c.Delete.Prompt = &b
+			return nil
+		},
+	},
+	{
+		key:   "set.ttl",
+		env:   "PDA_TTL",
+		def:   "0s",
+		usage: "default set --ttl",
+		get:   func(c *config) string { return c.Set.TTL },
+		set: func(c *config, v string) error {
+			if v != "" {
+				if _, err := time.ParseDuration(v); err != nil {
+					return fmt.Errorf("set.ttl must be a duration such as 24h or 30m")
+				}
+			}
+			c.Set.TTL = v
+			return nil
+		},
+	},
+	{
+		key:   "lock.timeout",
+		env:   "PDA_LOCK_TIMEOUT",
+		def:   "10s",
+		usage: "default --lock-timeout",
+		get:   func(c *config) string { return c.Lock.Timeout },
+		set: func(c *config, v string) error {
+			if v != "" {
+				if _, err := time.ParseDuration(v); err != nil {
+					return fmt.Errorf("lock.timeout must be a duration such as 10s or 1m")
+				}
+			}
+			c.Lock.Timeout = v
+			return nil
+		},
+	},
+	{
+		key:   "gc.auto",
+		env:   "PDA_GC_AUTO",
+		def:   "false",
+		usage: "run gc after a restore or bulk delete touches gc.threshold entries",
+		get: func(c *config) string {
+			if c.GC.Auto == nil {
+				return ""
+			}
+			return strconv.FormatBool(*c.GC.Auto)
+		},
+		set: func(c *config, v string) error {
+			if v == "" {
+				c.GC.Auto = nil
+				return nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("gc.auto must be true or false")
+			}
+			c.GC.Auto = &b
+			return nil
+		},
+	},
+	{
+		key:   "gc.threshold",
+		env:   "PDA_GC_THRESHOLD",
+		def:   "10000",
+		usage: "entries a restore or bulk delete must touch before gc.auto runs",
+		get: func(c *config) string {
+			if c.GC.Threshold == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.GC.Threshold)
+		},
+		set: func(c *config, v string) error {
+			if v == "" {
+				c.GC.Threshold = 0
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return fmt.Errorf("gc.threshold must be a positive number of entries")
+			}
+			c.GC.Threshold = n
+			return nil
+		},
+	},
+	{
+		key:   "journal.size",
+		env:   "PDA_JOURNAL_SIZE",
+		def:   "100",
+		usage: "writes per db kept for pda undo; 0 turns the journal off",
+		get: func(c *config) string {
+			if c.Journal.Size == nil {
+				return ""
+			}
+			return strconv.Itoa(*c.Journal.Size)
+		},
+		set: func(c *config, v string) error {
+			if v == "" {
+				c.Journal.Size = nil
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("journal.size must be a number of writes, or 0")
+			}
+			c.Journal.Size = &n
+			return nil
+		},
+	},
+	{
+		key:   "trash.retention",
+		env:   "PDA_TRASH_RETENTION",
+		def:   "168h",
+		usage: "how long deleted keys stay in pda trash; 0s turns the trash off",
+		get:   func(c *config) string { return c.Trash.Retention },
+		set: func(c *config, v string) error {
+			if v != "" {
+				if _, err := time.ParseDuration(v); err != nil {
+					return fmt.Errorf("trash.retention must be a duration such as 168h or 30m")
+				}
+			}
+			c.Trash.Retention = v
+			return nil
+		},
+	},
+	{
+		key:   "snapshot.retention",
+		env:   "PDA_SNAPSHOT_RETENTION",
+		def:   "10",
+		usage: "snapshots kept per db; 0 turns automatic snapshots off",
+		get: func(c *config) string {
+			if c.Snapshot.Retention == nil {
+				return ""
+			}
+			return strconv.Itoa(*c.Snapshot.Retention)
+		},
+		set: func(c *config, v string) error {
+			if v == "" {
+				c.Snapshot.Retention = nil
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("snapshot.retention must be a number of snapshots, or 0")
+			}
+			c.Snapshot.Retention = &n
+			return nil
+		},
+	},
+}
+
+var loadedConfig *config
+
+// configPath returns $PDA_HOME/config.toml, or config.toml in the user
+// config directory ($XDG_CONFIG_HOME/pda on Linux).
+func configPath() (string, error) {
+	if home := os.Getenv(envHome); home != "" {
+		return filepath.Join(home, configFile), nil
+	}
+	return gap.NewScope(gap.User, "pda").ConfigPath(configFile)
+}
+
+func readConfigFile() (*config, error) {
+	c := &config{}
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := toml.DecodeFile(path, c); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("cannot read %s: %w; fix it with pda config edit", nicePath(path), err)
+	}
+	return c, nil
+}
+
+func writeConfigFile(c *config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := toml.NewEncoder(f).Encode(c); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// currentConfig returns the config file with environment overrides applied.
+// It is read once per process.
+func currentConfig() (*config, error) {
+	if loadedConfig != nil {
+		return loadedConfig, nil
+	}
+	c, err := readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range configSettings {
+		v, ok := os.LookupEnv(s.env)
+		if !ok {
+			continue
+		}
+		if err := s.set(c, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.env, err)
+		}
+	}
+	loadedConfig = c
+	return c, nil
+}
+
+// configValue returns the effective value for key, falling back to its
+// built-in default.
+func configValue(key string) (string, error) {
+	s, err := findSetting(key)
+	if err != nil {
+		return "", err
+	}
+	c, err := currentConfig()
+	if err != nil {
+		return "", err
+	}
+	if v := s.get(c); v != "" {
+		return v, nil
+	}
+	return s.def, nil
+}
+
+func findSetting(key string) (configSetting, error) {
+	for _, s := range configSettings {
+		if s.key == key {
+			return s, nil
+		}
+	}
+	keys := make([]string, 0, len(configSettings))
+	for _, s := range configSettings {
+		keys = append(keys, s.key)
+	}
+	return configSetting{}, fmt.Errorf("unknown config key %q; use one of %s", key, strings.Join(keys, ", "))
+}
+
+// defaultDB is the db used when none is named. The config has already been
+// validated by rootCmd's PersistentPreRunE, so errors cannot occur here.
+func defaultDB() string {
+	db, err := configValue("db")
+	if err != nil || db == "" {
+		return "default"
+	}
+	return db
+}
+
+// isConfigCmd reports whether cmd is config or one of its subcommands.
+func isConfigCmd(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == configCmd {
+			return true
+		}
+	}
+	return false
+}
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Get and set configuration.",
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print the effective value of a config key.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  configGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:         "set KEY [VALUE]",
+	Short:       "Set a config key in the config file. Omit VALUE to unset it.",
+	Args:        cobra.RangeArgs(1, 2),
+	Annotations: writesData,
+	RunE:        configSet,
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in $VISUAL or $EDITOR.",
+	Long: `Open the config file in $VISUAL or $EDITOR (vi if neither is set). The
+file is not parsed first, so edit can fix a config that no longer loads.`,
+	Args:        cobra.NoArgs,
+	Annotations: writesData,
+	RunE:        configEdit,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every config key with its effective value.",
+	Args:  cobra.NoArgs,
+	RunE:  configList,
+}
+
+func configGet(cmd *cobra.Command, args []string) error {
+	v, err := configValue(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), v)
+	return nil
+}
+
+func configSet(cmd *cobra.Command, args []string) error {
+	s, err := findSetting(args[0])
+	if err != nil {
+		return err
+	}
+	c, err := readConfigFile()
+	if err != nil {
+		return err
+	}
+	var v string
+	if len(args) == 2 {
+		v = args[1]
+	}
+	if err := s.set(c, v); err != nil {
+		return err
+	}
+	return writeConfigFile(c)
+}
+
+func configEdit(cmd *cobra.Command, args []string) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	fields := strings.Fields(editor)
+	edit := exec.Command(fields[0], append(fields[1:], path)...)
+	edit.Stdin, edit.Stdout, edit.Stderr = cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr()
+	if err := edit.Run(); err != nil {
+		return err
+	}
+	_, err = readConfigFile()
+	return err
+}
+
+func configList(cmd *cobra.Command, args []string) error {
+	for _, s := range configSettings {
+		v, err := configValue(s.key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s = %s\n", s.key, v)
+	}
+	return nil
+}
+
+func init() {
+	var keys strings.Builder
+	for _, s := range configSettings {
+		fmt.Fprintf(&keys, "  %-15s %s (env %s)\n", s.key, s.usage, s.env)
+	}
+	configCmd.Long = "Get and set configuration.\n\nKeys:\n" + keys.String()
+	configCmd.AddCommand(configGetCmd, configSetCmd, configEditCmd, configListCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBrokenConfigOnlyBlocksNonConfigCommands(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(envHome, home)
+	if err := os.WriteFile(filepath.Join(home, configFile), []byte("db = [unclosed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loadedConfig = nil
+	defer func() { loadedConfig = nil }()
+
+	tests := []struct {
+		cmd     string
+		wantErr bool
+	}{
+		{"config", false},
+		{"config set", false},
+		{"config get", false},
+		{"config edit", false},
+		{"get", true},
+		{"list", true},
+	}
+	for _, tt := range tests {
+		cmd, _, err := rootCmd.Find(strings.Fields(tt.cmd))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = rootCmd.PersistentPreRunE(cmd, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("pre-run of %q = %v, want error %v", tt.cmd, err, tt.wantErr)
+		}
+	}
+}
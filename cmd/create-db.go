@@ -0,0 +1,135 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// createDbCmd represents the create-db command
+var createDbCmd = &cobra.Command{
+	Use:         "create-db DB",
+	Short:       "Create a database.",
+	Args:        cobra.ExactArgs(1),
+	Annotations: writesData,
+	RunE:        createDb,
+}
+
+func createDb(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	dbName, err := store.parseDB(args[0], false)
+	if err != nil {
+		return err
+	}
+	if isMemStore(dbName) {
+		return fmt.Errorf("@%s is in memory; in-memory stores are created on first use", dbName)
+	}
+	path, err := store.path(dbName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("@%s already exists", dbName)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	encrypt, err := cmd.Flags().GetBool("encrypt")
+	if err != nil {
+		return err
+	}
+
+	backend, err := cmd.Flags().GetString("backend")
+	if err != nil {
+		return err
+	}
+	versions, err := cmd.Flags().GetInt("history")
+	if err != nil {
+		return err
+	}
+	if versions < 1 {
+		return fmt.Errorf("--history must be at least 1")
+	}
+
+	switch backend {
+	case backendBadger:
+		backend = ""
+	case backendBolt:
+		if encrypt {
+			return fmt.Errorf("the %s backend does not support --encrypt", backendBolt)
+		}
+		if versions > 1 {
+			return fmt.Errorf("the %s backend does not support --history", backendBolt)
+		}
+	default:
+		return fmt.Errorf("unsupported backend %q; use %q or %q", backend, backendBadger, backendBolt)
+	}
+
+	meta := storeMeta{Backend: backend}
+	if versions > 1 {
+		meta.Versions = versions
+	}
+	if encrypt {
+		if _, err := loadKeyMaterial(); err != nil {
+			return err
+		}
+		salt, err := newSalt()
+		if err != nil {
+			return err
+		}
+		meta.Encrypted = true
+		meta.Salt = salt
+	}
+
+	if err := os.MkdirAll(path, 0o750); err != nil {
+		return err
+	}
+	if err := writeStoreMeta(path, meta); err != nil {
+		os.RemoveAll(path)
+		return err
+	}
+	db, err := store.open(dbName)
+	if err != nil {
+		os.RemoveAll(path)
+		return err
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+
+	if encrypt {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Created encrypted @%s\n", dbName)
+	} else {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Created @%s\n", dbName)
+	}
+	return nil
+}
+
+func init() {
+	createDbCmd.Flags().String("backend", backendBadger, "storage backend: badger, or bolt for a single-file store")
+	createDbCmd.Flags().Int("history", 1, "number of versions of each key to keep for pda history, get --at and revert")
+	createDbCmd.Flags().Bool("encrypt", false, "encrypt keys, values and metadata with a key derived from --keyfile or a passphrase")
+	rootCmd.AddCommand(createDbCmd)
+}
@@ -0,0 +1,404 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep stores open in the background so other commands skip reopening them.",
+	Long: `Keep stores open in the background so other commands skip reopening them.
+
+While the daemon is running, every other pda command sends its reads and
+writes over a unix socket in the user runtime directory instead of opening
+the store itself. When the daemon is not running, or PDA_NO_DAEMON is set,
+commands open stores directly.
+
+Without the daemon a command that only reads a store still locks out
+writers while it has the store open, so a long read makes writers wait up
+to --lock-timeout. Through the daemon, readers and writers share the store.
+
+In-memory stores, addressed as KEY@:mem:NAME, live in the daemon until it
+exits.`,
+	Args:        cobra.NoArgs,
+	Annotations: writesData,
+	RunE:        runDaemon,
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	idle, err := cmd.Flags().GetDuration("idle-timeout")
+	if err != nil {
+		return err
+	}
+	socket, err := daemonSocket()
+	if err != nil {
+		return err
+	}
+	if client := dialDaemon(); client != nil {
+		client.Close()
+		return fmt.Errorf("a daemon is already listening on %s", socket)
+	}
+	os.Remove(socket)
+
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(socket, 0o600); err != nil {
+		ln.Close()
+		return err
+	}
+
+	d := &daemon{pool: newStorePool(idle)}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		ln.Close()
+	}()
+	go d.pool.reap()
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Listening on %s\n", socket)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			return err
+		}
+		go d.serve(conn)
+	}
+	d.pool.closeAll()
+	return nil
+}
+
+type daemon struct {
+	pool *storePool
+}
+
+func (d *daemon) serve(conn net.Conn) {
+	c := &daemonConn{pool: d.pool, handles: map[uint64]string{}, txs: map[uint64]Tx{}}
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Daemon", c); err != nil {
+		conn.Close()
+		return
+	}
+	srv.ServeConn(conn)
+	c.cleanup()
+}
+
+// daemonConn is the RPC service for one client connection. It tracks the
+// stores and transactions the client has open so they can be released if
+// the client goes away.
+type daemonConn struct {
+	pool    *storePool
+	mu      sync.Mutex
+	next    uint64
+	handles map[uint64]string
+	txs     map[uint64]Tx
+}
+
+func (c *daemonConn) backend(handle uint64) (Backend, error) {
+	c.mu.Lock()
+	path, ok := c.handles[handle]
+	c.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown store handle %d", handle)
+	}
+	return c.pool.get(path)
+}
+
+func (c *daemonConn) tx(id uint64) (Tx, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tx, ok := c.txs[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown transaction %d", id)
+	}
+	return tx, nil
+}
+
+func (c *daemonConn) endTx(id uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.txs, id)
+}
+
+func (c *daemonConn) Open(args DaemonArgs, reply *uint64) error {
+	if _, err := c.pool.acquire(args.Name, args.Path, args.Meta, args.StoreKey, args.Timeout); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	c.handles[c.next] = args.Path
+	*reply = c.next
+	return nil
+}
+
+func (c *daemonConn) Close(args DaemonArgs, reply *bool) error {
+	c.mu.Lock()
+	path, ok := c.handles[args.Handle]
+	delete(c.handles, args.Handle)
+	c.mu.Unlock()
+	if ok {
+		c.pool.release(path)
+	}
+	return nil
+}
+
+func (c *daemonConn) Release(args DaemonArgs, reply *bool) error {
+	return c.pool.evict(args.Path)
+}
+
+func (c *daemonConn) MemStores(args DaemonArgs, reply *[]string) error {
+	*reply = c.pool.memStores()
+	return nil
+}
+
+func (c *daemonConn) Sync(args DaemonArgs, reply *bool) error {
+	db, err := c.backend(args.Handle)
+	if err != nil {
+		return err
+	}
+	return db.Sync()
+}
+
+func (c *daemonConn) Collect(args DaemonArgs, reply *int64) error {
+	db, err := c.backend(args.Handle)
+	if err != nil {
+		return err
+	}
+	gc, ok := capability[collector](db)
+	if !ok {
+		return errors.New("store does not support garbage collection")
+	}
+	freed, err := gc.Collect(args.Flatten)
+	*reply = freed
+	return err
+}
+
+func (c *daemonConn) DiskUsage(args DaemonArgs, reply *DiskUsage) error {
+	db, err := c.backend(args.Handle)
+	if err != nil {
+		return err
+	}
+	s, ok := capability[sizer](db)
+	if !ok {
+		return errors.New("store cannot report its disk usage")
+	}
+	usage, err := s.DiskUsage()
+	*reply = usage
+	return err
+}
+
+func (c *daemonConn) History(args DaemonArgs, reply *[]Revision) error {
+	db, err := c.backend(args.Handle)
+	if err != nil {
+		return err
+	}
+	h, ok := capability[historian](db)
+	if !ok {
+		return errors.New("store keeps no history")
+	}
+	revs, err := h.History(args.Key)
+	*reply = revs
+	return err
+}
+
+func (c *daemonConn) Backup(args DaemonArgs, reply *DaemonBackup) error {
+	s, err := c.snapshotter(args.Handle)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	version, err := s.Backup(&buf, args.Since)
+	if err != nil {
+		return err
+	}
+	*reply = DaemonBackup{Data: buf.Bytes(), Version: version}
+	return nil
+}
+
+func (c *daemonConn) Load(args DaemonArgs, reply *bool) error {
+	s, err := c.snapshotter(args.Handle)
+	if err != nil {
+		return err
+	}
+	return s.Load(bytes.NewReader(args.Data))
+}
+
+func (c *daemonConn) Replace(args DaemonArgs, reply *bool) error {
+	s, err := c.snapshotter(args.Handle)
+	if err != nil {
+		return err
+	}
+	return s.Replace(bytes.NewReader(args.Data))
+}
+
+func (c *daemonConn) Wait(args DaemonArgs, reply *bool) error {
+	db, err := c.backend(args.Handle)
+	if err != nil {
+		return err
+	}
+	w, ok := capability[watcher](db)
+	if !ok {
+		return errors.New("store cannot be watched")
+	}
+	return w.Wait(args.Key, args.Timeout)
+}
+
+func (c *daemonConn) snapshotter(handle uint64) (snapshotter, error) {
+	db, err := c.backend(handle)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := capability[snapshotter](db)
+	if !ok {
+		return nil, errNoSnapshots
+	}
+	return s, nil
+}
+
+func (c *daemonConn) Begin(args DaemonArgs, reply *uint64) error {
+	db, err := c.backend(args.Handle)
+	if err != nil {
+		return err
+	}
+	tx, err := db.NewTx(args.Update)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	c.txs[c.next] = tx
+	*reply = c.next
+	return nil
+}
+
+func (c *daemonConn) Get(args DaemonArgs, reply *Entry) error {
+	tx, err := c.tx(args.Tx)
+	if err != nil {
+		return err
+	}
+	e, err := tx.Get(args.Key)
+	if err != nil {
+		return err
+	}
+	*reply = e
+	return nil
+}
+
+func (c *daemonConn) Set(args DaemonArgs, reply *bool) error {
+	tx, err := c.tx(args.Tx)
+	if err != nil {
+		return err
+	}
+	return tx.Set(args.Entry)
+}
+
+func (c *daemonConn) Delete(args DaemonArgs, reply *bool) error {
+	tx, err := c.tx(args.Tx)
+	if err != nil {
+		return err
+	}
+	return tx.Delete(args.Key)
+}
+
+func (c *daemonConn) Iterate(args DaemonArgs, reply *[]Entry) error {
+	tx, err := c.tx(args.Tx)
+	if err != nil {
+		return err
+	}
+	return tx.Iterate(args.Iter, func(e Entry) error {
+		*reply = append(*reply, e)
+		return nil
+	})
+}
+
+func (c *daemonConn) Commit(args DaemonArgs, reply *bool) error {
+	tx, err := c.tx(args.Tx)
+	if err != nil {
+		return err
+	}
+	c.endTx(args.Tx)
+	return tx.Commit()
+}
+
+func (c *daemonConn) Discard(args DaemonArgs, reply *bool) error {
+	tx, err := c.tx(args.Tx)
+	if err != nil {
+		return nil
+	}
+	c.endTx(args.Tx)
+	tx.Discard()
+	return nil
+}
+
+func (c *daemonConn) Flush(args DaemonArgs, reply *bool) error {
+	db, err := c.backend(args.Handle)
+	if err != nil {
+		return err
+	}
+	wb := db.NewBatch()
+	defer wb.Cancel()
+	for _, e := range args.Entries {
+		if err := wb.Set(e); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
+func (c *daemonConn) cleanup() {
+	c.mu.Lock()
+	txs, handles := c.txs, c.handles
+	c.txs, c.handles = map[uint64]Tx{}, map[uint64]string{}
+	c.mu.Unlock()
+	for _, tx := range txs {
+		tx.Discard()
+	}
+	for _, path := range handles {
+		c.pool.release(path)
+	}
+}
+
+func init() {
+	daemonCmd.Flags().Duration("idle-timeout", 5*time.Minute, "close stores that have not been used for this long")
+	rootCmd.AddCommand(daemonCmd)
+}
@@ -26,16 +26,16 @@ import (
 	"os"
 	"strings"
 
-	"github.com/dgraph-io/badger/v4"
 	"github.com/spf13/cobra"
 )
 
 // delCmd represents the set command
 var delCmd = &cobra.Command{
-	Use:   "del KEY[@DB]",
-	Short: "Delete a key. Optionally specify a db.",
-	Args:  cobra.ExactArgs(1),
-	RunE:  del,
+	Use:         "del KEY[@DB]",
+	Short:       "Delete a key. Optionally specify a db.",
+	Args:        cobra.ExactArgs(1),
+	Annotations: writesData,
+	RunE:        del,
 }
 
 func del(cmd *cobra.Command, args []string) error {
@@ -45,20 +45,22 @@ func del(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	prompt, err := confirmDeletes()
+	if err != nil {
+		return err
+	}
 
 	targetKey, err := formatKeyForPrompt(store, args[0])
 	if err != nil {
 		return err
 	}
 
-	if !force {
-		var confirm string
-		message := fmt.Sprintf("Are you sure you want to delete %q? (y/n)", targetKey)
-		fmt.Println(message)
-		if _, err := fmt.Scanln(&confirm); err != nil {
+	if !force && prompt {
+		ok, err := confirmPrompt(cmd, fmt.Sprintf("Are you sure you want to delete %q?", targetKey))
+		if err != nil {
 			return err
 		}
-		if strings.ToLower(confirm) != "y" {
+		if !ok {
 			fmt.Fprintf(os.Stderr, "Did not delete %q\n", targetKey)
 			return nil
 		}
@@ -68,7 +70,7 @@ func del(cmd *cobra.Command, args []string) error {
 		key:      args[0],
 		readonly: false,
 		sync:     false,
-		transact: func(tx *badger.Txn, k []byte) error {
+		transact: func(tx Tx, k []byte) error {
 			return tx.Delete(k)
 		},
 	}
@@ -24,8 +24,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"path/filepath"
-	"strings"
+	"slices"
 
 	"github.com/spf13/cobra"
 	"os"
@@ -33,54 +32,82 @@ import (
 
 // delDbCmd represents the set command
 var delDbCmd = &cobra.Command{
-	Use:   "delete-db DB",
-	Short: "Delete a database.",
-	Args:  cobra.ExactArgs(1),
-	RunE:  delDb,
+	Use:         "delete-db DB",
+	Short:       "Delete a database.",
+	Args:        cobra.ExactArgs(1),
+	Annotations: writesData,
+	RunE:        delDb,
 }
 
 func delDb(cmd *cobra.Command, args []string) error {
 	store := &Store{}
-	var notFound errNotFound
+	dbName, err := store.parseDB(args[0], false)
+	if err != nil {
+		return err
+	}
 	path, err := store.FindStore(args[0])
-	if errors.As(err, &notFound) {
-		fmt.Fprintf(os.Stderr, "%q does not exist, %s\n", args[0], err.Error())
-		os.Exit(1)
+	if err != nil {
+		var notFound errNotFound
+		if errors.As(err, &notFound) {
+			return fmt.Errorf("%q does not exist, %s", args[0], err.Error())
+		}
+		return err
 	}
+	if isMemStore(path) {
+		mem, err := memStores()
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(mem, path) {
+			return fmt.Errorf("%q does not exist", args[0])
+		}
+	}
+
+	nicepath := nicePath(path)
+
+	force, err := cmd.Flags().GetBool("force")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "unexpected error: %s", err.Error())
-		os.Exit(1)
+		return err
 	}
 
-	var confirm string
-	home, err := os.UserHomeDir()
-	nicepath := path
-	if err == nil && strings.HasPrefix(path, home) {
-		nicepath = filepath.Join("~", strings.TrimPrefix(nicepath, home))
+	prompt, err := confirmDeletes()
+	if err != nil {
+		return err
 	}
 
-	force, err := cmd.Flags().GetBool("force")
+	noSnapshot, err := cmd.Flags().GetBool("no-snapshot")
 	if err != nil {
 		return err
 	}
 
-	if force {
-		return executeDeletion(path, nicepath)
+	if force || !prompt {
+		return executeDeletion(cmd, store, dbName, path, nicepath, noSnapshot)
 	}
 
-	message := fmt.Sprintf("Are you sure you want to delete '%s'? (y/n)", nicepath)
-	fmt.Println(message)
-	if _, err := fmt.Scanln(&confirm); err != nil {
+	ok, err := confirmPrompt(cmd, fmt.Sprintf("Are you sure you want to delete '%s'?", nicepath))
+	if err != nil {
 		return err
 	}
-	if strings.ToLower(confirm) == "y" {
-		return executeDeletion(path, nicepath)
+	if ok {
+		return executeDeletion(cmd, store, dbName, path, nicepath, noSnapshot)
 	}
 	fmt.Fprintf(os.Stderr, "Did not delete %q\n", nicepath)
 	return nil
 }
 
-func executeDeletion(path, nicepath string) error {
+func executeDeletion(cmd *cobra.Command, store *Store, dbName, path, nicepath string, noSnapshot bool) error {
+	if !noSnapshot {
+		if err := safetySnapshot(cmd, store, dbName, "before-delete-db"); err != nil {
+			return err
+		}
+	}
+	if err := releaseStore(path); err != nil {
+		return err
+	}
+	if isMemStore(path) {
+		fmt.Fprintf(os.Stderr, "Deleted @%s\n", path)
+		return nil
+	}
 	if err := os.RemoveAll(path); err != nil {
 		return err
 	}
@@ -90,5 +117,6 @@ func executeDeletion(path, nicepath string) error {
 
 func init() {
 	delDbCmd.Flags().BoolP("force", "f", false, "Force delete without confirmation")
+	delDbCmd.Flags().Bool("no-snapshot", false, "Do not snapshot the db before deleting it")
 	rootCmd.AddCommand(delDbCmd)
 }
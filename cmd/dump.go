@@ -1,34 +1,54 @@
 package cmd
 
 import (
+	"bufio"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"unicode/utf8"
 
-	"github.com/dgraph-io/badger/v4"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 type dumpEntry struct {
-	Key       string `json:"key"`
-	Value     string `json:"value"`
-	Encoding  string `json:"encoding,omitempty"`
-	Secret    bool   `json:"secret,omitempty"`
-	ExpiresAt *int64 `json:"expires_at,omitempty"`
+	Key       string            `json:"key"`
+	Type      string            `json:"type,omitempty"`
+	Value     string            `json:"value"`
+	ValueType string            `json:"value_type,omitempty"`
+	Items     []string          `json:"items,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Encoding  string            `json:"encoding,omitempty"`
+	Secret    bool              `json:"secret,omitempty"`
+	ExpiresAt *int64            `json:"expires_at,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Note      string            `json:"note,omitempty"`
 }
 
 var dumpCmd = &cobra.Command{
 	Use:   "dump [DB]",
 	Short: "Dump all key/value pairs as NDJSON",
-	Args:  cobra.MaximumNArgs(1),
-	RunE:  dump,
+	Long: `Dump all key/value pairs as NDJSON, or with --format badger as a badger
+backup stream.
+
+A badger dump keeps every entry with its version and history, and secret
+values stay sealed, and it is much faster to write and load. Once written it
+prints the version to pass to --since next time, so that a later dump holds
+only what changed since. Load badger dumps with pda restore into the db they
+came from, or into a new one, in the order they were taken.
+
+Badger decrypts an encrypted db as it writes the stream, so encrypted dbs
+cannot be dumped with --format badger; use pda snapshot, which keeps them
+encrypted, instead.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: dump,
 }
 
 func dump(cmd *cobra.Command, args []string) error {
 	store := &Store{}
-	targetDB := "@default"
+	targetDB := "@" + defaultDB()
 	if len(args) == 1 {
 		rawArg := args[0]
 		dbName, err := store.parseDB(rawArg, false)
@@ -59,58 +79,85 @@ func dump(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	into, err := cmd.Flags().GetString("into")
+	if err != nil {
+		return err
+	}
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return err
+	}
+	since, err := cmd.Flags().GetUint64("since")
+	if err != nil {
+		return err
+	}
+	switch {
+	case format != "ndjson" && format != "badger":
+		return fmt.Errorf("unsupported format %q; use ndjson or badger", format)
+	case format == "badger" && into != "":
+		return fmt.Errorf("--into copies entries as stored already; it cannot be combined with --format badger")
+	case format != "badger" && cmd.Flags().Changed("since"):
+		return fmt.Errorf("--since needs --format badger")
+	case format == "badger":
+		return dumpBadger(cmd, store, targetDB, since)
+	case into != "":
+		return dumpInto(cmd, store, targetDB, into)
+	}
 
+	keys := newKeyring()
 	trans := TransactionArgs{
 		key:      targetDB,
 		readonly: true,
 		sync:     true,
-		transact: func(tx *badger.Txn, k []byte) error {
-			opts := badger.DefaultIteratorOptions
-			opts.PrefetchSize = 64
-			it := tx.NewIterator(opts)
-			defer it.Close()
-			for it.Rewind(); it.Valid(); it.Next() {
-				item := it.Item()
-				key := item.KeyCopy(nil)
-				meta := item.UserMeta()
-				isSecret := meta&metaSecret != 0
-				if isSecret && !includeSecret {
-					continue
+		transact: func(tx Tx, k []byte) error {
+			// Collections are written once the keys have been walked, as
+			// their elements are read with iterators of their own.
+			var collections []Entry
+			annotations, err := readAnnotations(tx)
+			if err != nil {
+				return err
+			}
+			err = tx.Iterate(IterOptions{Values: true}, func(e Entry) error {
+				if e.Meta&metaKind != 0 {
+					collections = append(collections, e)
+					return nil
 				}
-				expiresAt := item.ExpiresAt()
-				if err := item.Value(func(v []byte) error {
-					entry := dumpEntry{
-						Key:    string(key),
-						Secret: isSecret,
-					}
-					if expiresAt > 0 {
-						ts := int64(expiresAt)
-						entry.ExpiresAt = &ts
-					}
-					switch mode {
-					case "base64":
-						encodeBase64(&entry, v)
-					case "text":
-						if err := encodeText(&entry, key, v); err != nil {
-							return err
-						}
-					case "auto":
-						if utf8.Valid(v) {
-							entry.Encoding = "text"
-							entry.Value = string(v)
-						} else {
-							encodeBase64(&entry, v)
-						}
-					}
-					payload, err := json.Marshal(entry)
-					if err != nil {
-						return err
-					}
-					fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+				isSecret := e.Meta&metaSecret != 0
+				if isSecret && !includeSecret {
 					return nil
-				}); err != nil {
+				}
+				v, err := keys.reveal(string(e.Key), e.Meta, e.Value)
+				if err != nil {
+					return err
+				}
+				entry, err := newDumpEntry(e, v, mode)
+				if err != nil {
+					return err
+				}
+				a := annotations[string(e.Key)]
+				entry.Labels, entry.Note = a.Labels, a.Note
+				payload, err := json.Marshal(entry)
+				if err != nil {
 					return err
 				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(payload))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, e := range collections {
+				entry, err := newCollectionDumpEntry(tx, e, mode)
+				if err != nil {
+					return err
+				}
+				a := annotations[string(e.Key)]
+				entry.Labels, entry.Note = a.Labels, a.Note
+				payload, err := json.Marshal(entry)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(payload))
 			}
 			return nil
 		},
@@ -119,12 +166,209 @@ func dump(cmd *cobra.Command, args []string) error {
 	return store.Transaction(trans)
 }
 
+// dumpInto copies every entry of src into another store exactly as stored,
+// secrets included. It is how an in-memory store is kept before it vanishes.
+func dumpInto(cmd *cobra.Command, store *Store, src, rawDst string) error {
+	dst, err := store.parseDB(rawDst, false)
+	if err != nil {
+		return err
+	}
+	if "@"+dst == src {
+		return fmt.Errorf("cannot dump %s into itself", src)
+	}
+	db, err := store.open(dst)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	wb := db.NewBatch()
+	defer wb.Cancel()
+	var copied int
+	err = store.Transaction(TransactionArgs{
+		key:      src,
+		readonly: true,
+		sync:     true,
+		transact: func(tx Tx, k []byte) error {
+			err := tx.Iterate(IterOptions{Values: true}, func(e Entry) error {
+				copied++
+				return wb.Set(e)
+			})
+			if err != nil {
+				return err
+			}
+			// The elements of collections and the labels of keys are
+			// internal keys, so they are not in the entries above.
+			for _, prefix := range []string{elemPrefix, annotationPrefix} {
+				err := tx.Iterate(IterOptions{Prefix: []byte(prefix), Values: true}, func(e Entry) error {
+					return wb.Set(e)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := wb.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Copied %d entries from %s into @%s\n", copied, src, dst)
+	return nil
+}
+
+// dumpBadger writes the versions of src after since as a badger backup
+// stream. Badger skips versions up to and including since, so the version
+// Backup returns is the since of the next dump as is.
+func dumpBadger(cmd *cobra.Command, store *Store, src string, since uint64) error {
+	out := cmd.OutOrStdout()
+	if f, ok := out.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return fmt.Errorf("refusing to write a badger dump to a terminal; redirect it to a file")
+	}
+	meta, err := snapshotMeta(store, src[1:])
+	if err != nil {
+		return err
+	}
+	if meta.Encrypted {
+		return fmt.Errorf("%s is encrypted and a badger dump would hold it in plaintext; use pda snapshot create instead", src)
+	}
+	db, err := store.openReadOnly(src[1:])
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	s, ok := capability[snapshotter](db)
+	if !ok {
+		return errNoSnapshots
+	}
+	w := bufio.NewWriter(out)
+	last, err := s.Backup(w, since)
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if last <= since {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Nothing in %s has changed since version %d\n", src, since)
+		return nil
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Dumped %s up to version %d; use --since %d for the next incremental dump\n", src, last, last)
+	return nil
+}
+
 func init() {
+	dumpCmd.Flags().String("format", "ndjson", "output format: ndjson, or badger for a badger backup stream")
+	dumpCmd.Flags().Uint64("since", 0, "with --format badger, only dump versions after this one")
 	dumpCmd.Flags().StringP("encoding", "e", "auto", "value encoding: auto, base64, or text")
 	dumpCmd.Flags().Bool("secret", false, "Include entries marked as secret")
+	dumpCmd.Flags().String("into", "", "copy every entry, secrets included, into this db instead of printing")
 	rootCmd.AddCommand(dumpCmd)
 }
 
+// newDumpEntry describes e, whose plaintext value is v, with the value
+// encoded according to mode (auto, base64 or text).
+func newDumpEntry(e Entry, v []byte, mode string) (dumpEntry, error) {
+	entry := dumpEntry{
+		Key:    string(e.Key),
+		Secret: e.Meta&metaSecret != 0,
+	}
+	if t := valueTypeOf(e.Meta); t != typeNone {
+		entry.ValueType = t.String()
+	}
+	if e.ExpiresAt > 0 {
+		ts := int64(e.ExpiresAt)
+		entry.ExpiresAt = &ts
+	}
+	switch mode {
+	case "base64":
+		encodeBase64(&entry, v)
+	case "text":
+		if err := encodeText(&entry, e.Key, v); err != nil {
+			return entry, err
+		}
+	case "auto":
+		if utf8.Valid(v) {
+			entry.Encoding = "text"
+			entry.Value = string(v)
+		} else {
+			encodeBase64(&entry, v)
+		}
+	}
+	return entry, nil
+}
+
+// newCollectionDumpEntry describes the collection e with its elements:
+// a list's values in order as items, a set's members as items, and a
+// hash's fields. Values taken with pop --visibility and not yet
+// acknowledged go at the front of a list, where they would return.
+func newCollectionDumpEntry(tx Tx, e Entry, mode string) (dumpEntry, error) {
+	entry := dumpEntry{Key: string(e.Key), Type: kindName(e.Meta)}
+	if e.ExpiresAt > 0 {
+		ts := int64(e.ExpiresAt)
+		entry.ExpiresAt = &ts
+	}
+	c, err := decodeCollectionHead(e.Value)
+	if err != nil {
+		return entry, fmt.Errorf("key %q: %w", e.Key, err)
+	}
+	var names, values, taken [][]byte
+	err = eachElem(tx, e.Key, c, func(name, value []byte) error {
+		switch {
+		case e.Meta&metaKind != kindList:
+			names = append(names, name)
+			values = append(values, value)
+		case name[0] == visibleElem && len(value) >= 8:
+			taken = append(taken, value[8:])
+		case name[0] == queuedElem:
+			values = append(values, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return entry, err
+	}
+	if e.Meta&metaKind == kindSet {
+		values, names = names, nil
+	} else {
+		values = append(taken, values...)
+	}
+
+	encoding := mode
+	if mode == "auto" {
+		encoding = "text"
+		for _, v := range values {
+			if !utf8.Valid(v) {
+				encoding = "base64"
+				break
+			}
+		}
+	}
+	encoded := make([]string, len(values))
+	for i, v := range values {
+		var item dumpEntry
+		if encoding == "base64" {
+			encodeBase64(&item, v)
+		} else if err := encodeText(&item, e.Key, v); err != nil {
+			return entry, err
+		}
+		encoded[i] = item.Value
+	}
+	entry.Encoding = encoding
+	if names == nil {
+		entry.Items = encoded
+		return entry, nil
+	}
+	entry.Fields = make(map[string]string, len(names))
+	for i, name := range names {
+		entry.Fields[string(name)] = encoded[i]
+	}
+	return entry, nil
+}
+
 func encodeBase64(entry *dumpEntry, v []byte) {
 	entry.Value = base64.StdEncoding.EncodeToString(v)
 	entry.Encoding = "base64"
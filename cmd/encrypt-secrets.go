@@ -0,0 +1,102 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// encryptSecretsCmd represents the encrypt-secrets command
+var encryptSecretsCmd = &cobra.Command{
+	Use:         "encrypt-secrets [DB]",
+	Short:       "Encrypt secrets that were stored in plaintext. Optionally specify a db.",
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: writesData,
+	RunE:        encryptSecrets,
+}
+
+func encryptSecrets(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	targetDB := "@" + defaultDB()
+	if len(args) == 1 {
+		rawArg := args[0]
+		dbName, err := store.parseDB(rawArg, false)
+		if err != nil {
+			return err
+		}
+		if _, err := store.FindStore(dbName); err != nil {
+			var notFound errNotFound
+			if errors.As(err, &notFound) {
+				return fmt.Errorf("%q does not exist, %s", rawArg, err.Error())
+			}
+			return err
+		}
+		targetDB = "@" + dbName
+	}
+
+	keys := newKeyring()
+	var upgraded int
+	trans := TransactionArgs{
+		key:      targetDB,
+		readonly: false,
+		sync:     true,
+		transact: func(tx Tx, k []byte) error {
+			var pending []Entry
+			err := tx.Iterate(IterOptions{Values: true}, func(e Entry) error {
+				if e.Meta&metaSecret == 0 || e.Meta&metaEncrypted != 0 {
+					return nil
+				}
+				sealed, err := keys.seal(e.Value)
+				if err != nil {
+					return err
+				}
+				e.Value = sealed
+				e.Meta |= metaEncrypted
+				pending = append(pending, e)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, entry := range pending {
+				if err := tx.Set(entry); err != nil {
+					return err
+				}
+			}
+			upgraded = len(pending)
+			return nil
+		},
+	}
+
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Encrypted %d secrets in %s\n", upgraded, targetDB)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(encryptSecretsCmd)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc [DB]",
+	Short: "Reclaim disk space left by overwritten, deleted and expired keys.",
+	Long: `Reclaim disk space left by overwritten, deleted and expired keys.
+
+Drops the elements of lists, hashes and sets that have been deleted,
+overwritten or have expired, once neither the trash nor the journal holds
+them, then runs value log garbage collection on a db until nothing more can
+be reclaimed, and reports how many bytes were freed. --flatten compacts the
+whole LSM tree first, which is slower but lets more space be reclaimed.
+
+Set gc.auto in the config to run this automatically after a restore or bulk
+delete that touches at least gc.threshold entries.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: writesData,
+	RunE:        gc,
+}
+
+func gc(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		return err
+	}
+	flatten, err := cmd.Flags().GetBool("flatten")
+	if err != nil {
+		return err
+	}
+	if all && len(args) > 0 {
+		return fmt.Errorf("cannot use --all with a db")
+	}
+
+	dbs := []string{"@" + defaultDB()}
+	if len(args) == 1 {
+		dbs = args
+	}
+	if all {
+		names, err := store.AllStores()
+		if err != nil {
+			return err
+		}
+		dbs = names
+	}
+
+	for _, rawArg := range dbs {
+		name, err := store.parseDB(rawArg, false)
+		if err != nil {
+			return err
+		}
+		if _, err := store.FindStore(name); err != nil {
+			var notFound errNotFound
+			if errors.As(err, &notFound) {
+				return fmt.Errorf("%q does not exist, %s", rawArg, err.Error())
+			}
+			return err
+		}
+		db, err := store.open(name)
+		if err != nil {
+			return err
+		}
+		err = collectStore(cmd.OutOrStdout(), db, name, flatten)
+		db.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectStore runs gc on an open store and reports what it freed.
+func collectStore(w io.Writer, db Backend, name string, flatten bool) error {
+	c, ok := capability[collector](db)
+	if !ok {
+		return fmt.Errorf("@%s does not support garbage collection", name)
+	}
+	swept, err := sweepElems(db)
+	if err != nil {
+		return fmt.Errorf("cannot gc @%s: %w", name, err)
+	}
+	if swept > 0 {
+		fmt.Fprintf(w, "@%s: dropped %d elements of deleted collections\n", name, swept)
+	}
+	freed, err := c.Collect(flatten)
+	if err != nil {
+		return fmt.Errorf("cannot gc @%s: %w", name, err)
+	}
+	fmt.Fprintf(w, "@%s: reclaimed %s\n", name, formatSize(freed))
+	return nil
+}
+
+// autoGC runs gc on an open store after a command has written or deleted n
+// entries, if gc.auto is on and n reaches gc.threshold.
+func autoGC(cmd *cobra.Command, db Backend, name string, n int) error {
+	auto, err := configValue("gc.auto")
+	if err != nil {
+		return err
+	}
+	threshold, err := configValue("gc.threshold")
+	if err != nil {
+		return err
+	}
+	limit, err := strconv.Atoi(threshold)
+	if err != nil {
+		return err
+	}
+	if on, _ := strconv.ParseBool(auto); !on || n < limit {
+		return nil
+	}
+	return collectStore(cmd.ErrOrStderr(), db, name, false)
+}
+
+// formatSize prints n bytes with a binary unit, e.g. 1.5 MiB.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	gcCmd.Flags().Bool("all", false, "gc every db")
+	gcCmd.Flags().Bool("flatten", false, "compact the whole LSM tree before collecting")
+	rootCmd.AddCommand(gcCmd)
+}
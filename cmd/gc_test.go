@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSweepElems(t *testing.T) {
+	db, err := openBadgerMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	head := func(id uint64) []byte {
+		return collectionHead{id: id, head: listStart, tail: listStart + 1}.encode()
+	}
+	trashed, _ := json.Marshal(trashRecord{Entry: Entry{Key: []byte("trashed"), Value: head(3), Meta: kindSet}})
+	journaled, _ := json.Marshal(journalRecord{Op: "set", Key: []byte("journaled"), Prev: &Entry{Key: []byte("journaled"), Value: head(4), Meta: kindList}})
+	tx, _ := db.NewTx(true)
+	for _, e := range []Entry{
+		{Key: []byte("live"), Value: head(1), Meta: kindHash},
+		{Key: elemKey([]byte("live"), 1, []byte("f"))},
+		{Key: []byte("overwritten"), Value: []byte("plain")},
+		{Key: elemKey([]byte("overwritten"), 2, []byte("x"))},
+		{Key: elemKey([]byte("gone\x00key"), 5, []byte("x"))},
+		{Key: trashKey([]byte("trashed")), Value: trashed},
+		{Key: elemKey([]byte("trashed"), 3, []byte("m"))},
+		{Key: journalKey(1), Value: journaled},
+		{Key: elemKey([]byte("journaled"), 4, listElem(queuedElem, listStart))},
+	} {
+		if err := tx.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	swept, err := sweepElems(db)
+	if err != nil || swept != 2 {
+		t.Fatalf("sweepElems() = %d, %v, want 2", swept, err)
+	}
+	tx, _ = db.NewTx(false)
+	defer tx.Discard()
+	tests := []struct {
+		key  []byte
+		want bool
+	}{
+		{elemKey([]byte("live"), 1, []byte("f")), true},
+		{elemKey([]byte("overwritten"), 2, []byte("x")), false},
+		{elemKey([]byte("gone\x00key"), 5, []byte("x")), false},
+		{elemKey([]byte("trashed"), 3, []byte("m")), true},
+		{elemKey([]byte("journaled"), 4, listElem(queuedElem, listStart)), true},
+	}
+	for _, tt := range tests {
+		_, err := tx.Get(tt.key)
+		if got := err == nil; got != tt.want {
+			t.Errorf("%q kept = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
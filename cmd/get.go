@@ -24,7 +24,6 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/dgraph-io/badger/v4"
 	"github.com/spf13/cobra"
 )
 
@@ -32,40 +31,79 @@ import (
 var getCmd = &cobra.Command{
 	Use:   "get KEY[@DB]",
 	Short: "Get a value for a key. Optionally specify a db.",
-	Args:  cobra.ExactArgs(1),
-	RunE:  get,
+	Long: `Get a value for a key. Optionally specify a db.
+
+--path prints one field of a value that holds a JSON document, e.g.
+--path .db.host or --path .hosts[0]. Strings are printed as they are and
+anything else as JSON.`,
+	Args: cobra.ExactArgs(1),
+	RunE: get,
 }
 
 func get(cmd *cobra.Command, args []string) error {
 	store := &Store{}
 
+	flags := cmd.Flags()
+	if flags.Changed("version") || flags.Changed("at") {
+		rev, err := findRevision(cmd, store, args[0])
+		if err != nil {
+			return err
+		}
+		if rev.Deleted {
+			return fmt.Errorf("%q was deleted at version %d", args[0], rev.Version)
+		}
+		return printValue(cmd, store, args[0], rev.Meta, rev.Value)
+	}
+
 	var v []byte
 	var meta byte
 	trans := TransactionArgs{
 		key:      args[0],
 		readonly: true,
 		sync:     false,
-		transact: func(tx *badger.Txn, k []byte) error {
-			item, err := tx.Get(k)
+		transact: func(tx Tx, k []byte) error {
+			e, err := tx.Get(k)
 			if err != nil {
 				return err
 			}
-			meta = item.UserMeta()
-			v, err = item.ValueCopy(nil)
-			return err
+			meta = e.Meta
+			v = e.Value
+			return nil
 		},
 	}
 
 	if err := store.Transaction(trans); err != nil {
 		return err
 	}
+	return printValue(cmd, store, args[0], meta, v)
+}
 
+// printValue prints the stored value of key, or the field at --path,
+// refusing secrets without --secret and collections, which have no single
+// value.
+func printValue(cmd *cobra.Command, store *Store, key string, meta byte, v []byte) error {
+	if err := checkKind([]byte(key), Entry{Meta: meta}, 0); err != nil {
+		return err
+	}
 	includeSecret, err := cmd.Flags().GetBool("secret")
 	if err != nil {
 		return err
 	}
 	if meta&metaSecret != 0 && !includeSecret {
-		return fmt.Errorf("%q is marked secret; re-run with --secret to display it", args[0])
+		return fmt.Errorf("%q is marked secret; re-run with --secret to display it", key)
+	}
+	v, err = newKeyring().reveal(key, meta, v)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("path") {
+		path, err := cmd.Flags().GetString("path")
+		if err != nil {
+			return err
+		}
+		if v, err = jsonField(key, v, path); err != nil {
+			return err
+		}
 	}
 
 	binary, err := cmd.Flags().GetBool("include-binary")
@@ -80,5 +118,8 @@ func get(cmd *cobra.Command, args []string) error {
 func init() {
 	getCmd.Flags().BoolP("include-binary", "b", false, "include binary data in text output")
 	getCmd.Flags().Bool("secret", false, "display values marked as secret")
+	getCmd.Flags().Uint64("version", 0, "get this version of the key, from pda history")
+	getCmd.Flags().String("at", "", "get the version current at this time")
+	getCmd.Flags().String("path", "", "print only this field of a JSON value, e.g. .db.host")
 	rootCmd.AddCommand(getCmd)
 }
@@ -0,0 +1,196 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// hsetCmd represents the hset command
+var hsetCmd = &cobra.Command{
+	Use:   "hset KEY[@DB] FIELD VALUE [FIELD VALUE...]",
+	Short: "Set fields of a hash. Optionally specify a db.",
+	Long: `Set fields of a hash, creating it if need be.
+
+A hash holds named fields under one key, each stored on its own, so that
+changing one field does not rewrite the others.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 3 || len(args)%2 == 0 {
+			return fmt.Errorf("requires a key and one or more FIELD VALUE pairs")
+		}
+		return nil
+	},
+	Annotations: writesData,
+	RunE:        hset,
+}
+
+// hgetCmd represents the hget command
+var hgetCmd = &cobra.Command{
+	Use:   "hget KEY[@DB] FIELD",
+	Short: "Get a field of a hash. Optionally specify a db.",
+	Args:  cobra.ExactArgs(2),
+	RunE:  hget,
+}
+
+// hdelCmd represents the hdel command
+var hdelCmd = &cobra.Command{
+	Use:         "hdel KEY[@DB] FIELD...",
+	Short:       "Delete fields of a hash. Optionally specify a db.",
+	Args:        cobra.MinimumNArgs(2),
+	Annotations: writesData,
+	RunE:        hdel,
+}
+
+// hgetallCmd represents the hgetall command
+var hgetallCmd = &cobra.Command{
+	Use:   "hgetall KEY[@DB]",
+	Short: "Print every field of a hash, a tab and its value, one per line.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  hgetall,
+}
+
+func hset(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	var size uint64
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: false,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			e, c, found, err := readCollection(tx, k, kindHash)
+			if err != nil {
+				return err
+			}
+			for i := 1; i < len(args); i += 2 {
+				if err := putElem(tx, k, &c, []byte(args[i]), []byte(args[i+1])); err != nil {
+					return err
+				}
+			}
+			size = c.tail - c.head
+			if err := writeCollection(tx, e, c); err != nil {
+				return err
+			}
+			return annotateSet(tx, k, !found, labelChange{})
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.ErrOrStderr(), holds(args[0], size, "field"))
+	return nil
+}
+
+func hget(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	binary, err := cmd.Flags().GetBool("include-binary")
+	if err != nil {
+		return err
+	}
+	var v []byte
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: true,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			_, c, found, err := readCollection(tx, k, kindHash)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("%q does not exist", args[0])
+			}
+			e, err := tx.Get(elemKey(k, c.id, []byte(args[1])))
+			if errors.Is(err, errKeyNotFound) {
+				return fmt.Errorf("%q has no field %q", args[0], args[1])
+			}
+			v = e.Value
+			return err
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	store.Print("%s", binary, v)
+	return nil
+}
+
+func hdel(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	var size uint64
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: false,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			e, c, found, err := readCollection(tx, k, kindHash)
+			if err != nil || !found {
+				return err
+			}
+			for _, field := range args[1:] {
+				if err := deleteElem(tx, k, &c, []byte(field)); err != nil {
+					return err
+				}
+			}
+			size = c.tail - c.head
+			return writeCollection(tx, e, c)
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.ErrOrStderr(), holds(args[0], size, "field"))
+	return nil
+}
+
+func hgetall(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	binary, err := cmd.Flags().GetBool("include-binary")
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: true,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			_, c, found, err := readCollection(tx, k, kindHash)
+			if err != nil || !found {
+				return err
+			}
+			return eachElem(tx, k, c, func(field, value []byte) error {
+				store.PrintTo(out, "%s\t%s\n", binary, field, value)
+				return nil
+			})
+		},
+	}
+	return store.Transaction(trans)
+}
+
+func init() {
+	hgetCmd.Flags().BoolP("include-binary", "b", false, "include binary data in text output")
+	hgetallCmd.Flags().BoolP("include-binary", "b", false, "include binary data in text output")
+	rootCmd.AddCommand(hsetCmd, hgetCmd, hdelCmd, hgetallCmd)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// historyRetentionCmd represents the history-retention command
+var historyRetentionCmd = &cobra.Command{
+	Use:   "history-retention DB [VERSIONS]",
+	Short: "Show or set how many versions of each key a db keeps.",
+	Long: `Show or set how many versions of each key a db keeps.
+
+With more than one version kept, every write also records when it was made,
+for pda history, get --at and revert. 1 keeps only the current version.
+Only badger dbs on disk keep history.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: historyRetention,
+}
+
+func historyRetention(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	path, err := store.FindStore(args[0])
+	if err != nil {
+		var notFound errNotFound
+		if errors.As(err, &notFound) {
+			return fmt.Errorf("%q does not exist, %s", args[0], err.Error())
+		}
+		return err
+	}
+	if isMemStore(path) {
+		return fmt.Errorf("%q is in memory and keeps no history", args[0])
+	}
+	meta, err := readStoreMeta(path)
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		fmt.Fprintln(cmd.OutOrStdout(), max(meta.Versions, 1))
+		return nil
+	}
+
+	if readOnlyFlag {
+		return errReadOnly
+	}
+	versions, err := strconv.Atoi(args[1])
+	if err != nil || versions < 1 {
+		return fmt.Errorf("VERSIONS must be a positive number")
+	}
+	if meta.Backend == backendBolt {
+		return fmt.Errorf("the %s backend keeps no history", backendBolt)
+	}
+	if err := releaseStore(path); err != nil {
+		return err
+	}
+	meta.Versions = versions
+	if err := writeStoreMeta(path, meta); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%q now keeps %d versions of each key\n", args[0], versions)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(historyRetentionCmd)
+}
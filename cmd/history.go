@@ -0,0 +1,193 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history KEY[@DB]",
+	Short: "List the versions a db still holds for a key.",
+	Long: `List the versions a db still holds for a key, newest first.
+
+A db keeps only the current version of each key unless it was created with
+--history or changed with history-retention. Older versions are dropped as
+the store compacts, so more may show than the db is set to keep. Pass a
+version to get --version or revert --version.`,
+	Args: cobra.ExactArgs(1),
+	RunE: history,
+}
+
+func history(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	includeSecret, err := cmd.Flags().GetBool("secret")
+	if err != nil {
+		return err
+	}
+	revs, err := keyHistory(store, args[0])
+	if err != nil {
+		return err
+	}
+	if len(revs) == 0 {
+		return fmt.Errorf("%q has no history", args[0])
+	}
+
+	keys := newKeyring()
+	tw := table.NewWriter()
+	tw.SetOutputMirror(cmd.OutOrStdout())
+	tw.SetStyle(table.StyleLight)
+	tw.AppendHeader(table.Row{"Version", "Written", "Value", "TTL"})
+	for _, rev := range revs {
+		written := "-"
+		if !rev.Time.IsZero() {
+			written = rev.Time.UTC().Format(time.RFC3339)
+		}
+		value, ttl := "(deleted)", ""
+		if !rev.Deleted {
+			ttl = formatExpiry(rev.ExpiresAt)
+			if rev.Meta&metaSecret != 0 && !includeSecret {
+				value = "**********"
+			} else {
+				plain, err := keys.reveal(args[0], rev.Meta, rev.Value)
+				if err != nil {
+					return err
+				}
+				value = store.FormatBytes(false, plain)
+			}
+		}
+		tw.AppendRow(table.Row{rev.Version, written, value, ttl})
+	}
+	tw.Render()
+	return nil
+}
+
+// keyHistory returns the versions of a KEY[@DB], newest first.
+func keyHistory(store *Store, rawKey string) ([]Revision, error) {
+	k, dbName, err := store.parse(rawKey, true)
+	if err != nil {
+		return nil, err
+	}
+	db, err := store.openReadOnly(dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	h, ok := capability[historian](db)
+	if !ok {
+		return nil, fmt.Errorf("@%s keeps no history", dbName)
+	}
+	return h.History(k)
+}
+
+// findRevision picks a version of a KEY[@DB] by the --version or --at flag.
+// --at picks the newest version written at or before the time given.
+func findRevision(cmd *cobra.Command, store *Store, rawKey string) (Revision, error) {
+	version, err := cmd.Flags().GetUint64("version")
+	if err != nil {
+		return Revision{}, err
+	}
+	at, err := cmd.Flags().GetString("at")
+	if err != nil {
+		return Revision{}, err
+	}
+	var when time.Time
+	if at != "" {
+		if when, err = parseTimestamp(at); err != nil {
+			return Revision{}, err
+		}
+	}
+	if version == 0 && when.IsZero() {
+		return Revision{}, fmt.Errorf("pass --version N or --at TIMESTAMP")
+	}
+
+	revs, err := keyHistory(store, rawKey)
+	if err != nil {
+		return Revision{}, err
+	}
+	if version != 0 {
+		for _, rev := range revs {
+			if rev.Version == version {
+				return rev, nil
+			}
+		}
+		return Revision{}, fmt.Errorf("%q has no version %d; see pda history", rawKey, version)
+	}
+	if rev, ok := revisionAt(revs, when); ok {
+		return rev, nil
+	}
+	return Revision{}, fmt.Errorf("%q has no version written by %s; see pda history", rawKey, when.Format(time.RFC3339))
+}
+
+// revisionAt returns the newest of revs, which are newest first, written at
+// or before when.
+func revisionAt(revs []Revision, when time.Time) (Revision, bool) {
+	for _, rev := range revs {
+		if !rev.Time.IsZero() && !rev.Time.After(when) {
+			return rev, true
+		}
+	}
+	return Revision{}, false
+}
+
+// parseTimestamp accepts RFC 3339, a local date and time, a local date, or
+// seconds since the Unix epoch. A timestamp means the end of its last given
+// unit, so the whole-second times pda history prints find the versions
+// written during that second, and a bare date covers the whole day.
+func parseTimestamp(v string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0).Add(time.Second - time.Nanosecond), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		if !strings.Contains(v, ".") {
+			t = t.Add(time.Second - time.Nanosecond)
+		}
+		return t, nil
+	}
+	for _, layout := range []struct {
+		format string
+		unit   time.Duration
+	}{
+		{"2006-01-02 15:04:05", time.Second},
+		{"2006-01-02T15:04:05", time.Second},
+		{"2006-01-02 15:04", time.Minute},
+		{"2006-01-02", 24 * time.Hour},
+	} {
+		if t, err := time.ParseInLocation(layout.format, v, time.Local); err == nil {
+			return t.Add(layout.unit - time.Nanosecond), nil
+		}
+	}
+	return time.Time{}, errors.New("bad timestamp; use RFC 3339, YYYY-MM-DD[ HH:MM[:SS]] or Unix seconds")
+}
+
+func init() {
+	historyCmd.Flags().Bool("secret", false, "display values marked as secret")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevisionAt(t *testing.T) {
+	first := time.Date(2025, 3, 1, 12, 0, 5, 250_000_000, time.UTC)
+	second := time.Date(2025, 3, 1, 12, 0, 9, 900_000_000, time.UTC)
+	revs := []Revision{
+		{Entry: Entry{Version: 3}, Time: second},
+		{Entry: Entry{Version: 2}, Time: first},
+		{Entry: Entry{Version: 1}},
+	}
+	tests := []struct {
+		at      string
+		version uint64
+		ok      bool
+	}{
+		{first.Format(time.RFC3339), 2, true},
+		{second.Format(time.RFC3339), 3, true},
+		{first.Format(time.RFC3339Nano), 2, true},
+		{first.Add(-time.Nanosecond).Format(time.RFC3339Nano), 0, false},
+		{"2025-03-01T12:00:08Z", 2, true},
+		{"2025-03-01T12:00:04Z", 0, false},
+		{"2025-03-01T13:00:05+01:00", 2, true},
+		{"1740830405", 2, true},
+		{"1740830404", 0, false},
+		{second.In(time.Local).Format("2006-01-02 15:04:05"), 3, true},
+		{second.In(time.Local).Format("2006-01-02 15:04"), 3, true},
+		{"2025-03-02", 3, true},
+		{"2025-02-28", 0, false},
+	}
+	for _, tt := range tests {
+		when, err := parseTimestamp(tt.at)
+		if err != nil {
+			t.Fatalf("parseTimestamp(%q): %v", tt.at, err)
+		}
+		rev, ok := revisionAt(revs, when)
+		if ok != tt.ok || rev.Version != tt.version {
+			t.Errorf("--at %s picked version %d (found %v), want %d (found %v)", tt.at, rev.Version, ok, tt.version, tt.ok)
+		}
+	}
+}
+
+func TestParseTimestampRejectsGarbage(t *testing.T) {
+	for _, v := range []string{"", "yesterday", "2025-13-01", "12:00"} {
+		if _, err := parseTimestamp(v); err == nil {
+			t.Errorf("parseTimestamp(%q) succeeded", v)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// incrCmd represents the incr command
+var incrCmd = &cobra.Command{
+	Use:   "incr KEY[@DB] [BY]",
+	Short: "Add to a number stored in a key. Optionally specify a db.",
+	Long: `Add BY, 1 by default, to the integer or decimal stored in a key and print
+the result. The read and the write are one transaction, so concurrent
+incrs never lose an update. The key keeps its TTL and stays secret if it
+was. Decimals are exact, to as many places as the longer operand has.`,
+	Args:        cobra.RangeArgs(1, 2),
+	Annotations: writesData,
+	RunE:        counter(1),
+}
+
+// decrCmd represents the decr command
+var decrCmd = &cobra.Command{
+	Use:   "decr KEY[@DB] [BY]",
+	Short: "Subtract from a number stored in a key. Optionally specify a db.",
+	Long: `Subtract BY, 1 by default, from the integer or decimal stored in a key
+and print the result. It works as pda incr does.`,
+	Args:        cobra.RangeArgs(1, 2),
+	Annotations: writesData,
+	RunE:        counter(-1),
+}
+
+var decimalRe = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+
+// counter returns the RunE of incr (sign 1) or decr (sign -1).
+func counter(sign int) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		store := &Store{}
+		by := "1"
+		if len(args) == 2 {
+			by = args[1]
+		}
+		delta, byPlaces, err := parseDecimal(by)
+		if err != nil {
+			return fmt.Errorf("cannot add %q: %w", by, err)
+		}
+		if sign < 0 {
+			delta.Neg(delta)
+		}
+		init, err := cmd.Flags().GetString("init")
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("init") {
+			if _, _, err := parseDecimal(init); err != nil {
+				return fmt.Errorf("cannot start at %q: %w", init, err)
+			}
+		}
+		includeSecret, err := cmd.Flags().GetBool("secret")
+		if err != nil {
+			return err
+		}
+		defaultTTL, err := configValue("set.ttl")
+		if err != nil {
+			return err
+		}
+		ttl, err := time.ParseDuration(defaultTTL)
+		if err != nil {
+			return fmt.Errorf("set.ttl: %w", err)
+		}
+
+		keys := newKeyring()
+		var result string
+		var secret bool
+		trans := TransactionArgs{
+			key:      args[0],
+			readonly: false,
+			sync:     false,
+			transact: func(tx Tx, k []byte) error {
+				e, err := tx.Get(k)
+				created := errors.Is(err, errKeyNotFound)
+				var current []byte
+				switch {
+				case created && cmd.Flags().Changed("init"):
+					e = Entry{Key: k}
+					if ttl != 0 {
+						e.ExpiresAt = uint64(time.Now().Add(ttl).Unix())
+					}
+					current = []byte(init)
+				case errors.Is(err, errKeyNotFound):
+					return fmt.Errorf("%q does not exist; pass --init to start it", args[0])
+				case err != nil:
+					return err
+				default:
+					if err := checkKind(k, e, 0); err != nil {
+						return err
+					}
+					if current, err = keys.reveal(string(k), e.Meta, e.Value); err != nil {
+						return err
+					}
+				}
+				n, places, err := parseDecimal(string(current))
+				if err != nil {
+					return fmt.Errorf("%q does not hold a number: %w", args[0], err)
+				}
+				result = n.Add(n, delta).FloatString(max(places, byPlaces))
+				secret = e.Meta&metaSecret != 0
+				e.Value = []byte(result)
+				if t := valueTypeOf(e.Meta); e.Meta&metaKind == 0 {
+					if err := t.validate(e.Value); err != nil {
+						return fmt.Errorf("%q is typed %s: %w", args[0], t, err)
+					}
+				}
+				if e.Meta&metaEncrypted != 0 {
+					if e.Value, err = keys.seal(e.Value); err != nil {
+						return err
+					}
+				}
+				e.Version = 0
+				if err := tx.Set(e); err != nil {
+					return err
+				}
+				return annotateSet(tx, k, created, labelChange{})
+			},
+		}
+		if err := store.Transaction(trans); err != nil {
+			return err
+		}
+		if secret && !includeSecret {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Updated secret %q; re-run with --secret to print the result\n", args[0])
+			return nil
+		}
+		store.Print("%s", false, []byte(result))
+		return nil
+	}
+}
+
+// parseDecimal parses an integer or decimal, returning it along with the
+// number of digits after its decimal point.
+func parseDecimal(s string) (*big.Rat, int, error) {
+	s = strings.TrimSpace(s)
+	if !decimalRe.MatchString(s) {
+		return nil, 0, fmt.Errorf("not an integer or decimal")
+	}
+	n, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, 0, fmt.Errorf("not an integer or decimal")
+	}
+	places := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		places = len(s) - i - 1
+	}
+	return n, places, nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{incrCmd, decrCmd} {
+		c.Flags().String("init", "", "start a key that does not exist at this value, e.g. 0")
+		c.Flags().Bool("secret", false, "print the result even if the key is secret")
+		rootCmd.AddCommand(c)
+	}
+}
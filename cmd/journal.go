@@ -0,0 +1,248 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	endian "encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Every write transaction records what it changed in the store's journal so
+// that pda undo can put it back, and moves deleted keys to the trash.
+// journalHead holds the sequence numbers of the oldest journal record and
+// the next one, so that writes need not scan the journal to append to it.
+const (
+	journalPrefix = internalPrefix + "journal:"
+	journalHead   = internalPrefix + "journal-head"
+	trashPrefix   = internalPrefix + "trash:"
+)
+
+// journalRecord is one set or delete, along with the entry it replaced.
+// Prev is nil when the key did not exist.
+type journalRecord struct {
+	Op   string `json:"op"`
+	Key  []byte `json:"key"`
+	Time int64  `json:"time"`
+	Prev *Entry `json:"prev,omitempty"`
+}
+
+// trashRecord is a deleted key. TTL is the time it had left, in seconds, so
+// that a restore gives it the same again.
+type trashRecord struct {
+	Entry
+	DeletedAt int64 `json:"deleted_at"`
+	TTL       int64 `json:"ttl,omitempty"`
+}
+
+// journalTx wraps a write transaction, noting the previous value of every
+// key it changes. The notes are written to the journal on Commit.
+type journalTx struct {
+	Tx
+	size      int
+	retention time.Duration
+	records   []journalRecord
+}
+
+// newJournalTx wraps tx according to journal.size and trash.retention, or
+// returns tx as is when both are off.
+func newJournalTx(tx Tx) (Tx, error) {
+	v, err := configValue("journal.size")
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, fmt.Errorf("journal.size: %w", err)
+	}
+	if v, err = configValue("trash.retention"); err != nil {
+		return nil, err
+	}
+	retention, err := time.ParseDuration(v)
+	if err != nil {
+		return nil, fmt.Errorf("trash.retention: %w", err)
+	}
+	if size == 0 && retention <= 0 {
+		return tx, nil
+	}
+	return &journalTx{Tx: tx, size: size, retention: retention}, nil
+}
+
+func (t *journalTx) Set(e Entry) error {
+	if internalKey(e.Key) {
+		return t.Tx.Set(e)
+	}
+	prev, err := t.previous(e.Key)
+	if err != nil {
+		return err
+	}
+	if err := t.Tx.Set(e); err != nil {
+		return err
+	}
+	t.note("set", e.Key, prev)
+	return nil
+}
+
+func (t *journalTx) Delete(key []byte) error {
+	if internalKey(key) {
+		return t.Tx.Delete(key)
+	}
+	prev, err := t.previous(key)
+	if err != nil {
+		return err
+	}
+	if err := t.Tx.Delete(key); err != nil {
+		return err
+	}
+	if prev == nil {
+		return nil
+	}
+	if t.retention > 0 {
+		if err := t.trash(*prev); err != nil {
+			return err
+		}
+	}
+	t.note("del", key, prev)
+	return nil
+}
+
+func (t *journalTx) Commit() error {
+	if t.size > 0 && len(t.records) > 0 {
+		if err := t.writeJournal(); err != nil {
+			return err
+		}
+	}
+	return t.Tx.Commit()
+}
+
+func (t *journalTx) previous(key []byte) (*Entry, error) {
+	e, err := t.Tx.Get(key)
+	if errors.Is(err, errKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (t *journalTx) note(op string, key []byte, prev *Entry) {
+	t.records = append(t.records, journalRecord{Op: op, Key: key, Time: time.Now().Unix(), Prev: prev})
+}
+
+// trash keeps a deleted entry until trash.retention passes.
+func (t *journalTx) trash(e Entry) error {
+	now := time.Now()
+	rec := trashRecord{Entry: e, DeletedAt: now.Unix()}
+	if e.ExpiresAt > 0 {
+		rec.TTL = max(int64(e.ExpiresAt)-now.Unix(), 1)
+		rec.ExpiresAt = 0
+	}
+	v, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return t.Tx.Set(Entry{
+		Key:       trashKey(e.Key),
+		Value:     v,
+		ExpiresAt: uint64(now.Add(t.retention).Unix()),
+	})
+}
+
+// writeJournal appends this transaction's records after the newest in the
+// journal and drops the oldest beyond journal.size.
+func (t *journalTx) writeJournal() error {
+	first, next, err := t.head()
+	if err != nil {
+		return err
+	}
+	for _, rec := range t.records {
+		v, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if err := t.Tx.Set(Entry{Key: journalKey(next), Value: v}); err != nil {
+			return err
+		}
+		next++
+	}
+	for ; next-first > uint64(t.size); first++ {
+		if err := t.Tx.Delete(journalKey(first)); err != nil {
+			return err
+		}
+	}
+	head := endian.BigEndian.AppendUint64(endian.BigEndian.AppendUint64(nil, first), next)
+	return t.Tx.Set(Entry{Key: []byte(journalHead), Value: head})
+}
+
+// head returns the oldest and next sequence numbers of the journal, reading
+// them from the journal itself if journalHead is missing or stale.
+func (t *journalTx) head() (uint64, uint64, error) {
+	e, err := t.Tx.Get([]byte(journalHead))
+	if err == nil && len(e.Value) == 16 {
+		return endian.BigEndian.Uint64(e.Value), endian.BigEndian.Uint64(e.Value[8:]), nil
+	}
+	if err != nil && !errors.Is(err, errKeyNotFound) {
+		return 0, 0, err
+	}
+	seqs, _, err := readJournal(t.Tx)
+	if err != nil || len(seqs) == 0 {
+		return 1, 1, err
+	}
+	return seqs[0], seqs[len(seqs)-1] + 1, nil
+}
+
+// journalKey zero-pads seq so that journal keys sort in order.
+func journalKey(seq uint64) []byte {
+	return fmt.Appendf(nil, "%s%020d", journalPrefix, seq)
+}
+
+func journalSeq(key []byte) (uint64, error) {
+	return strconv.ParseUint(string(key[len(journalPrefix):]), 10, 64)
+}
+
+func trashKey(key []byte) []byte {
+	return append([]byte(trashPrefix), key...)
+}
+
+// readJournal returns the journal of tx, oldest first.
+func readJournal(tx Tx) ([]uint64, []journalRecord, error) {
+	var seqs []uint64
+	var records []journalRecord
+	err := tx.Iterate(IterOptions{Prefix: []byte(journalPrefix), Values: true}, func(e Entry) error {
+		seq, err := journalSeq(e.Key)
+		if err != nil {
+			return err
+		}
+		var rec journalRecord
+		if err := json.Unmarshal(e.Value, &rec); err != nil {
+			return fmt.Errorf("corrupt journal entry %d: %w", seq, err)
+		}
+		seqs = append(seqs, seq)
+		records = append(records, rec)
+		return nil
+	})
+	return seqs, records, err
+}
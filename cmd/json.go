@@ -0,0 +1,389 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// jsonCmd represents the json command
+var jsonCmd = &cobra.Command{
+	Use:   "json",
+	Short: "Change values that hold JSON documents.",
+	Long: `Change values that hold JSON documents.
+
+See also get --path and set --path, which read and write one field of a
+document.`,
+}
+
+var jsonMergeCmd = &cobra.Command{
+	Use:   "merge KEY[@DB] [PATCH]",
+	Short: "Apply a JSON merge patch (RFC 7386) to a value, from PATCH or Stdin.",
+	Long: `Apply a JSON merge patch (RFC 7386) to a value, from PATCH or Stdin.
+
+Members of the patch replace those of the value, objects are merged
+member by member, and a member set to null is removed. A key that does not
+exist yet is created. If the value is not valid JSON, it is left as it is.`,
+	Args:        cobra.RangeArgs(1, 2),
+	Annotations: writesData,
+	RunE:        jsonMerge,
+}
+
+func jsonMerge(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	var raw []byte
+	if len(args) == 2 {
+		raw = []byte(args[1])
+	} else {
+		v, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		raw = v
+	}
+	patch, err := decodeJSON(raw)
+	if err != nil {
+		return fmt.Errorf("the patch is not valid JSON: %w", err)
+	}
+
+	keys := newKeyring()
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: false,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			e, err := patchJSON(tx, k, keys, func(doc any) (any, error) {
+				return mergePatch(doc, patch), nil
+			})
+			if err != nil {
+				return err
+			}
+			return tx.Set(e)
+		},
+	}
+	return store.Transaction(trans)
+}
+
+// patchJSON reads the JSON document at key and returns the entry to write
+// in its place after fn has changed it, keeping its secrecy and expiry.
+// A key that does not exist yet starts as null and comes back with no
+// meta or expiry.
+func patchJSON(tx Tx, key []byte, keys *keyring, fn func(doc any) (any, error)) (Entry, error) {
+	e, err := tx.Get(key)
+	var doc any
+	var indent bool
+	switch {
+	case errors.Is(err, errKeyNotFound):
+		e = Entry{Key: key}
+	case err != nil:
+		return e, err
+	default:
+		if err := checkKind(key, e, 0); err != nil {
+			return e, err
+		}
+		v, err := keys.reveal(string(key), e.Meta, e.Value)
+		if err != nil {
+			return e, err
+		}
+		if doc, err = decodeJSON(v); err != nil {
+			return e, fmt.Errorf("%q does not hold valid JSON: %w", key, err)
+		}
+		indent = bytes.Contains(bytes.TrimSpace(v), []byte("\n"))
+	}
+	if doc, err = fn(doc); err != nil {
+		return e, err
+	}
+	if e.Value, err = encodeJSON(doc, indent); err != nil {
+		return e, err
+	}
+	if t := valueTypeOf(e.Meta); t.validate(e.Value) != nil {
+		return e, fmt.Errorf("%q is typed %s, not json", key, t)
+	}
+	if e.Meta&metaEncrypted != 0 {
+		if e.Value, err = keys.seal(e.Value); err != nil {
+			return e, err
+		}
+	}
+	e.Version = 0
+	return e, nil
+}
+
+// decodeJSON parses a single JSON value, keeping numbers as written.
+func decodeJSON(v []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(v))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return doc, nil
+}
+
+// encodeJSON writes doc compactly, or indented by two spaces, without
+// escaping HTML characters.
+func encodeJSON(doc any, indent bool) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// mergePatch applies patch to doc as RFC 7386 describes.
+func mergePatch(doc, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]any)
+	if !ok {
+		d = map[string]any{}
+	}
+	for name, v := range p {
+		if v == nil {
+			delete(d, name)
+		} else {
+			d[name] = mergePatch(d[name], v)
+		}
+	}
+	return d
+}
+
+// jsonStep is one step of a path into a JSON document: an object member
+// by name or an array element by index.
+type jsonStep struct {
+	name  string
+	index int
+	array bool
+}
+
+func (s jsonStep) String() string {
+	if s.array {
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+	return "." + s.name
+}
+
+// parseJSONPath parses a path such as .db.hosts[0].name. A member whose
+// name has dots or brackets in it can be written as ["a.b"], and . alone
+// is the whole document.
+func parseJSONPath(path string) ([]jsonStep, error) {
+	if path == "." {
+		return nil, nil
+	}
+	bad := func(why string) ([]jsonStep, error) {
+		return nil, fmt.Errorf("bad path %q: %s", path, why)
+	}
+	var steps []jsonStep
+	for rest := path; rest != ""; {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			if end == 1 {
+				return bad("empty member name")
+			}
+			steps = append(steps, jsonStep{name: rest[1:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return bad("missing ]")
+			}
+			inner := rest[1:end]
+			if strings.HasPrefix(inner, `"`) {
+				// A quoted name may itself hold a ], so find the
+				// end of the string first.
+				var name string
+				dec := json.NewDecoder(strings.NewReader(rest[1:]))
+				if err := dec.Decode(&name); err != nil {
+					return bad("unterminated member name")
+				}
+				after := 1 + int(dec.InputOffset())
+				if after >= len(rest) || rest[after] != ']' {
+					return bad("missing ]")
+				}
+				steps = append(steps, jsonStep{name: name})
+				rest = rest[after+1:]
+				continue
+			}
+			n, err := strconv.Atoi(inner)
+			if err != nil || n < 0 {
+				return bad(fmt.Sprintf("%q is not an array index", inner))
+			}
+			steps = append(steps, jsonStep{index: n, array: true})
+			rest = rest[end+1:]
+		default:
+			return bad("expected . or [")
+		}
+	}
+	if len(steps) == 0 {
+		return bad("use . for the whole document")
+	}
+	return steps, nil
+}
+
+// lookupJSON returns the value at steps within doc.
+func lookupJSON(doc any, steps []jsonStep) (any, error) {
+	var at strings.Builder
+	for _, s := range steps {
+		switch v := doc.(type) {
+		case map[string]any:
+			member, ok := v[s.name]
+			if s.array || !ok {
+				return nil, fmt.Errorf("no %s at %s", s, pathOrRoot(at.String()))
+			}
+			doc = member
+		case []any:
+			if !s.array || s.index >= len(v) {
+				return nil, fmt.Errorf("no %s at %s", s, pathOrRoot(at.String()))
+			}
+			doc = v[s.index]
+		default:
+			return nil, fmt.Errorf("no %s at %s, which is %s", s, pathOrRoot(at.String()), jsonTypeName(doc))
+		}
+		at.WriteString(s.String())
+	}
+	return doc, nil
+}
+
+// setJSON returns doc with the value at steps set to v, creating objects
+// and arrays on the way as needed. An index one past the end of an array
+// appends to it.
+func setJSON(doc any, steps []jsonStep, v any, at string) (any, error) {
+	if len(steps) == 0 {
+		return v, nil
+	}
+	s := steps[0]
+	next := at + s.String()
+	if s.array {
+		arr, ok := doc.([]any)
+		if doc == nil {
+			ok = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("cannot set %s: %s is %s, not an array", next, pathOrRoot(at), jsonTypeName(doc))
+		}
+		if s.index > len(arr) {
+			return nil, fmt.Errorf("cannot set %s: %s has only %d elements", next, pathOrRoot(at), len(arr))
+		}
+		if s.index == len(arr) {
+			arr = append(arr, nil)
+		}
+		elem, err := setJSON(arr[s.index], steps[1:], v, next)
+		if err != nil {
+			return nil, err
+		}
+		arr[s.index] = elem
+		return arr, nil
+	}
+	obj, ok := doc.(map[string]any)
+	if doc == nil {
+		obj, ok = map[string]any{}, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot set %s: %s is %s, not an object", next, pathOrRoot(at), jsonTypeName(doc))
+	}
+	member, err := setJSON(obj[s.name], steps[1:], v, next)
+	if err != nil {
+		return nil, err
+	}
+	obj[s.name] = member
+	return obj, nil
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "the top level"
+	}
+	return path
+}
+
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return "null"
+	}
+}
+
+// jsonField returns the field at path within the JSON document v, held by
+// key: strings as they are and anything else as JSON.
+func jsonField(key string, v []byte, path string) ([]byte, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeJSON(v)
+	if err != nil {
+		return nil, fmt.Errorf("%q does not hold valid JSON: %w", key, err)
+	}
+	field, err := lookupJSON(doc, steps)
+	if err != nil {
+		return nil, fmt.Errorf("%q has %w", key, err)
+	}
+	if s, ok := field.(string); ok {
+		return []byte(s), nil
+	}
+	return encodeJSON(field, false)
+}
+
+// jsonArgValue reads a value given on the command line for set --path:
+// JSON if it parses as JSON, and otherwise a string.
+func jsonArgValue(v []byte) any {
+	if doc, err := decodeJSON(v); err == nil {
+		return doc
+	}
+	return string(bytes.TrimSuffix(v, []byte("\n")))
+}
+
+func init() {
+	jsonCmd.AddCommand(jsonMergeCmd)
+	rootCmd.AddCommand(jsonCmd)
+}
@@ -0,0 +1,285 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// labelCmd represents the label command
+var labelCmd = &cobra.Command{
+	Use:   "label KEY[@DB] [NAME=VALUE | NAME-]...",
+	Short: "Change or print the labels of a key without rewriting its value.",
+	Long: `Change or print the labels of a key without rewriting its value.
+
+NAME=VALUE sets a label and NAME- removes one; --note replaces the key's
+note, and --note "" removes it. With nothing to change, the key's labels
+are printed one per line.
+
+Labels and notes are shown by list --show-labels and --show-note, and
+list --label NAME=VALUE keeps only the keys with that label.`,
+	Args:        cobra.MinimumNArgs(1),
+	Annotations: writesData,
+	RunE:        label,
+}
+
+// annotationPrefix starts the keys holding each key's labels and note. They
+// are kept apart from the value so that labelling a key does not rewrite
+// it, and they outlive a delete so that undo and trash restore bring them
+// back. A set that creates a key afresh clears what it finds.
+const annotationPrefix = internalPrefix + "note:"
+
+func annotationKey(key []byte) []byte {
+	return append([]byte(annotationPrefix), key...)
+}
+
+type annotation struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Note   string            `json:"note,omitempty"`
+}
+
+func (a annotation) empty() bool {
+	return len(a.Labels) == 0 && a.Note == ""
+}
+
+// formatLabels joins labels as NAME=VALUE pairs in name order.
+func (a annotation) formatLabels() string {
+	pairs := make([]string, 0, len(a.Labels))
+	for _, name := range slices.Sorted(maps.Keys(a.Labels)) {
+		pairs = append(pairs, name+"="+a.Labels[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// readAnnotation returns the labels and note of key, if it has any.
+func readAnnotation(tx Tx, key []byte) (annotation, error) {
+	var a annotation
+	e, err := tx.Get(annotationKey(key))
+	if errors.Is(err, errKeyNotFound) {
+		return a, nil
+	}
+	if err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal(e.Value, &a); err != nil {
+		return a, fmt.Errorf("corrupt labels for %q: %w", key, err)
+	}
+	return a, nil
+}
+
+// writeAnnotation stores the labels and note of key, or removes them if a
+// is empty.
+func writeAnnotation(tx Tx, key []byte, a annotation) error {
+	if a.empty() {
+		err := tx.Delete(annotationKey(key))
+		if errors.Is(err, errKeyNotFound) {
+			return nil
+		}
+		return err
+	}
+	v, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return tx.Set(Entry{Key: annotationKey(key), Value: v})
+}
+
+// readAnnotations returns the labels and notes of every key in a store.
+func readAnnotations(tx Tx) (map[string]annotation, error) {
+	all := map[string]annotation{}
+	err := tx.Iterate(IterOptions{Prefix: []byte(annotationPrefix), Values: true}, func(e Entry) error {
+		var a annotation
+		key := string(e.Key[len(annotationPrefix):])
+		if err := json.Unmarshal(e.Value, &a); err != nil {
+			return fmt.Errorf("corrupt labels for %q: %w", key, err)
+		}
+		all[key] = a
+		return nil
+	})
+	return all, err
+}
+
+// labelChange is a change to a key's labels and note, from flags or
+// arguments.
+type labelChange struct {
+	set    map[string]string
+	remove []string
+	note   *string
+}
+
+func (c labelChange) empty() bool {
+	return len(c.set) == 0 && len(c.remove) == 0 && c.note == nil
+}
+
+func (c labelChange) apply(a annotation) annotation {
+	if a.Labels == nil && len(c.set) > 0 {
+		a.Labels = map[string]string{}
+	}
+	maps.Copy(a.Labels, c.set)
+	for _, name := range c.remove {
+		delete(a.Labels, name)
+	}
+	if c.note != nil {
+		a.Note = *c.note
+	}
+	return a
+}
+
+// parseLabel parses NAME=VALUE, or NAME- if removals are allowed.
+func parseLabel(s string, c *labelChange, allowRemove bool) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok && allowRemove && strings.HasSuffix(s, "-") {
+		name = strings.TrimSuffix(s, "-")
+	} else if !ok && allowRemove {
+		return fmt.Errorf("bad label %q; use NAME=VALUE, or NAME- to remove it", s)
+	} else if !ok {
+		return fmt.Errorf("bad label %q; use NAME=VALUE", s)
+	}
+	if name == "" || strings.ContainsAny(name, ",= ") {
+		return fmt.Errorf("bad label name %q", name)
+	}
+	if !ok {
+		c.remove = append(c.remove, name)
+		return nil
+	}
+	if c.set == nil {
+		c.set = map[string]string{}
+	}
+	c.set[name] = value
+	return nil
+}
+
+// labelFlags reads --label, if cmd has it, and --note into a labelChange.
+func labelFlags(cmd *cobra.Command) (labelChange, error) {
+	var c labelChange
+	if cmd.Flags().Lookup("label") != nil {
+		labels, err := cmd.Flags().GetStringArray("label")
+		if err != nil {
+			return c, err
+		}
+		for _, l := range labels {
+			if err := parseLabel(l, &c, false); err != nil {
+				return c, err
+			}
+		}
+	}
+	if cmd.Flags().Changed("note") {
+		note, err := cmd.Flags().GetString("note")
+		if err != nil {
+			return c, err
+		}
+		c.note = &note
+	}
+	return c, nil
+}
+
+func label(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	change, err := labelFlags(cmd)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args[1:] {
+		if err := parseLabel(arg, &change, true); err != nil {
+			return err
+		}
+	}
+	var a annotation
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: change.empty(),
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			if _, err := tx.Get(k); err != nil {
+				if errors.Is(err, errKeyNotFound) {
+					return fmt.Errorf("%q does not exist", args[0])
+				}
+				return err
+			}
+			if a, err = readAnnotation(tx, k); err != nil || change.empty() {
+				return err
+			}
+			a = change.apply(a)
+			return writeAnnotation(tx, k, a)
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	if change.empty() {
+		for _, name := range slices.Sorted(maps.Keys(a.Labels)) {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s=%s\n", name, a.Labels[name])
+		}
+	}
+	return nil
+}
+
+// annotateSet applies change to the labels and note of key as it is set,
+// starting afresh if the set creates the key.
+func annotateSet(tx Tx, key []byte, created bool, change labelChange) error {
+	if !created && change.empty() {
+		return nil
+	}
+	var a annotation
+	if !created {
+		var err error
+		if a, err = readAnnotation(tx, key); err != nil {
+			return err
+		}
+	}
+	return writeAnnotation(tx, key, change.apply(a))
+}
+
+// labelFilter is a list --label filter: NAME=VALUE, or NAME for any value.
+type labelFilter struct {
+	name  string
+	value *string
+}
+
+func parseLabelFilter(s string) (labelFilter, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if name == "" {
+		return labelFilter{}, fmt.Errorf("bad --label %q; use NAME=VALUE or NAME", s)
+	}
+	f := labelFilter{name: name}
+	if ok {
+		f.value = &value
+	}
+	return f, nil
+}
+
+func (f labelFilter) match(a annotation) bool {
+	v, ok := a.Labels[f.name]
+	return ok && (f.value == nil || v == *f.value)
+}
+
+func init() {
+	labelCmd.Flags().String("note", "", "replace the key's note")
+	rootCmd.AddCommand(labelCmd)
+}
@@ -23,6 +23,9 @@ package cmd
 
 import (
 	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
 	"github.com/spf13/cobra"
 )
 
@@ -36,14 +39,34 @@ var listDbsCmd = &cobra.Command{
 
 func listDbs(cmd *cobra.Command, args []string) error {
 	store := &Store{}
-	dbs, err := store.AllStores()
+	roots, err := store.roots()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	seen := map[string]bool{}
+	for _, root := range roots {
+		dbs, err := storesIn(root.dir)
+		if err != nil {
+			return err
+		}
+		for _, db := range dbs {
+			kind := root.kind
+			if seen[db] {
+				kind += " (shadowed)"
+			}
+			seen[db] = true
+			fmt.Fprintf(tw, "@%s\t%s\t%s\n", db, kind, nicePath(filepath.Join(root.dir, db)))
+		}
+	}
+	mem, err := memStores()
 	if err != nil {
 		return err
 	}
-	for _, db := range dbs {
-		fmt.Println("@" + db)
+	for _, db := range mem {
+		fmt.Fprintf(tw, "@%s\t%s\t%s\n", db, "memory", "(not persisted)")
 	}
-	return nil
+	return tw.Flush()
 }
 
 func init() {
@@ -24,8 +24,10 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
-	"github.com/dgraph-io/badger/v4"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
 )
@@ -33,13 +35,26 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list [DB]",
 	Short: "List the contents of a db.",
-	Args:  cobra.MaximumNArgs(1),
-	RunE:  list,
+	Long: `List the contents of a db.
+
+--where keeps only values of a type, set with set --type, and optionally
+only those that compare with a value as given, e.g. --where 'int > 10' or
+--where 'timestamp < 2026-01-01T00:00:00Z'. The operators are = != < <= >
+and >=. Numbers, durations and timestamps compare by size. Passing --where
+more than once keeps the values that match them all.
+
+--sort value orders the list by value instead of by key, typed values
+by type and then as their type compares.
+
+--label NAME=VALUE keeps only keys with that label (see pda label), and
+--label NAME those with the label at all.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: list,
 }
 
 func list(cmd *cobra.Command, args []string) error {
 	store := &Store{}
-	targetDB := "@default"
+	targetDB := "@" + defaultDB()
 	if len(args) == 1 {
 		rawArg := args[0]
 		dbName, err := store.parseDB(rawArg, false)
@@ -81,32 +96,55 @@ func list(cmd *cobra.Command, args []string) error {
 	}
 
 	placeholder := "**********"
+	keys := newKeyring()
+	needValues := flags.value || len(flags.where) > 0 || flags.sortByValue
+	var rows []listRow
 	trans := TransactionArgs{
 		key:      targetDB,
 		readonly: true,
 		sync:     true,
-		transact: func(tx *badger.Txn, k []byte) error {
-			opts := badger.DefaultIteratorOptions
-			opts.PrefetchSize = 10
-			opts.PrefetchValues = flags.value
-			it := tx.NewIterator(opts)
-			defer it.Close()
-			var valueBuf []byte
-			for it.Rewind(); it.Valid(); it.Next() {
-				item := it.Item()
-				key := string(item.KeyCopy(nil))
-				meta := item.UserMeta()
-				isSecret := meta&metaSecret != 0
-
-				var valueStr string
-				if flags.value && (!isSecret || flags.secrets) {
-					if err := item.Value(func(v []byte) error {
-						valueBuf = append(valueBuf[:0], v...)
+		transact: func(tx Tx, k []byte) error {
+			var annotations map[string]annotation
+			if flags.labels || flags.note || len(flags.withLabels) > 0 {
+				var err error
+				if annotations, err = readAnnotations(tx); err != nil {
+					return err
+				}
+			}
+			return tx.Iterate(IterOptions{Values: needValues}, func(e Entry) error {
+				key := string(e.Key)
+				isSecret := e.Meta&metaSecret != 0
+				for _, f := range flags.withLabels {
+					if !f.match(annotations[key]) {
 						return nil
-					}); err != nil {
+					}
+				}
+
+				var plain []byte
+				hidden := isSecret && !flags.secrets && e.Meta&metaKind == 0
+				if needValues && e.Meta&metaKind == 0 && !hidden {
+					var err error
+					if plain, err = keys.reveal(key, e.Meta, e.Value); err != nil {
 						return err
 					}
-					valueStr = store.FormatBytes(flags.binary, valueBuf)
+				}
+				for _, w := range flags.where {
+					// Secrets are left out unless they could be shown.
+					if hidden || !w.match(e.Meta, plain) {
+						return nil
+					}
+				}
+
+				var valueStr string
+				switch {
+				case !flags.value:
+				case e.Meta&metaKind != 0:
+					valueStr = describeCollection(e)
+				case hidden:
+				case valueTypeOf(e.Meta) == typeBytes && !flags.binary:
+					valueStr = fmt.Sprintf("(%d bytes)", len(plain))
+				default:
+					valueStr = store.FormatBytes(flags.binary, plain)
 				}
 
 				columns := make([]string, 0, len(columnKinds))
@@ -121,19 +159,33 @@ func list(cmd *cobra.Command, args []string) error {
 							columns = append(columns, valueStr)
 						}
 					case columnTTL:
-						columns = append(columns, formatExpiry(item.ExpiresAt()))
+						columns = append(columns, formatExpiry(e.ExpiresAt))
+					case columnVersion:
+						columns = append(columns, strconv.FormatUint(e.Version, 10))
+					case columnType:
+						columns = append(columns, typeName(e.Meta))
+					case columnLabels:
+						columns = append(columns, annotations[key].formatLabels())
+					case columnNote:
+						columns = append(columns, annotations[key].Note)
 					}
 				}
 				updateMaxContentWidths(maxContentWidths, columns)
-				tw.AppendRow(stringSliceToRow(columns))
-			}
-			return nil
+				rows = append(rows, listRow{columns: columns, meta: e.Meta, value: plain, hidden: hidden})
+				return nil
+			})
 		},
 	}
 
 	if err := store.Transaction(trans); err != nil {
 		return err
 	}
+	if flags.sortByValue {
+		slices.SortStableFunc(rows, compareRows)
+	}
+	for _, row := range rows {
+		tw.AppendRow(stringSliceToRow(row.columns))
+	}
 
 	applyColumnConstraints(tw, columnKinds, output, maxContentWidths)
 
@@ -141,12 +193,45 @@ func list(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// listRow is a row of list output, with what it is sorted by.
+type listRow struct {
+	columns []string
+	meta    byte
+	value   []byte
+	hidden  bool
+}
+
+// compareRows orders rows for --sort value: collections after plain
+// values, values of one type as that type compares, and values of
+// different types by the type's name. Rows whose values are hidden keep
+// their order at the end.
+func compareRows(a, b listRow) int {
+	if a.hidden || b.hidden {
+		return compareBool(a.hidden, b.hidden)
+	}
+	if a.meta&metaKind != 0 || b.meta&metaKind != 0 {
+		return int(a.meta&metaKind) - int(b.meta&metaKind)
+	}
+	ta, tb := valueTypeOf(a.meta), valueTypeOf(b.meta)
+	if ta != tb {
+		return strings.Compare(ta.String(), tb.String())
+	}
+	return compareValues(ta, a.value, b.value)
+}
+
 func init() {
 	listCmd.Flags().BoolVarP(&binary, "binary", "b", false, "include binary data in text output")
 	listCmd.Flags().BoolVarP(&secret, "secret", "S", false, "display values marked as secret")
 	listCmd.Flags().BoolVar(&noKeys, "no-keys", false, "suppress the key column")
 	listCmd.Flags().BoolVar(&noValues, "no-values", false, "suppress the value column")
 	listCmd.Flags().BoolVarP(&ttl, "ttl", "t", false, "append a TTL column when entries expire")
+	listCmd.Flags().BoolVar(&showVersion, "show-version", false, "append a column with each key's version, for set --if-version")
+	listCmd.Flags().BoolVar(&showType, "show-type", false, "append a column with each key's type, e.g. int, json or list")
+	listCmd.Flags().StringArrayVar(&where, "where", nil, "keep only values of a type, or that compare as given, e.g. 'int > 10'")
+	listCmd.Flags().StringArrayVar(&withLabel, "label", nil, "keep only keys with this label, as NAME=VALUE or NAME (repeatable)")
+	listCmd.Flags().BoolVar(&showLabels, "show-labels", false, "append a column with each key's labels")
+	listCmd.Flags().BoolVar(&showNote, "show-note", false, "append a column with each key's note")
+	listCmd.Flags().StringVar(&sortBy, "sort", "key", "order by key or by value")
 	listCmd.Flags().BoolVar(&noHeader, "no-header", false, "omit the header rows")
 	listCmd.Flags().VarP(&format, "format", "o", "render output format (table|csv|markdown|html)")
 	rootCmd.AddCommand(listCmd)
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
@@ -13,9 +15,17 @@ type ListArgs struct {
 	key     bool
 	value   bool
 	ttl     bool
+	version bool
+	kind    bool
+	labels  bool
+	note    bool
 	binary  bool
 	secrets bool
 	render  func(table.Writer)
+
+	where       []whereClause
+	sortByValue bool
+	withLabels  []labelFilter
 }
 
 // formatEnum implements pflag.Value for format selection.
@@ -39,31 +49,64 @@ func (e *formatEnum) Type() string {
 	return "format"
 }
 
-var (
-	binary   bool       = false
-	secret   bool       = false
-	noKeys   bool       = false
-	noValues bool       = false
-	ttl      bool       = false
-	noHeader bool       = false
-	format   formatEnum = "table"
-)
-
-func parseFlags(cmd *cobra.Command) (ListArgs, error) {
-	var renderFunc func(tw table.Writer)
-	switch format.String() {
+// renderer returns the table.Writer method for the format.
+func (e formatEnum) renderer() func(table.Writer) {
+	switch e {
 	case "csv":
-		renderFunc = func(tw table.Writer) { tw.RenderCSV() }
+		return func(tw table.Writer) { tw.RenderCSV() }
 	case "html":
-		renderFunc = func(tw table.Writer) { tw.RenderHTML() }
+		return func(tw table.Writer) { tw.RenderHTML() }
 	case "markdown":
-		renderFunc = func(tw table.Writer) { tw.RenderMarkdown() }
+		return func(tw table.Writer) { tw.RenderMarkdown() }
 	default:
-		renderFunc = func(tw table.Writer) { tw.Render() }
+		return func(tw table.Writer) { tw.Render() }
+	}
+}
+
+var (
+	binary      bool       = false
+	secret      bool       = false
+	noKeys      bool       = false
+	noValues    bool       = false
+	ttl         bool       = false
+	showVersion bool       = false
+	showType    bool       = false
+	where       []string   = nil
+	withLabel   []string   = nil
+	showLabels  bool       = false
+	showNote    bool       = false
+	sortBy      string     = "key"
+	noHeader    bool       = false
+	format      formatEnum = "table"
+)
+
+func parseFlags(cmd *cobra.Command) (ListArgs, error) {
+	if err := applyListConfig(cmd); err != nil {
+		return ListArgs{}, err
 	}
 
-	if noKeys && noValues && !ttl {
-		return ListArgs{}, fmt.Errorf("no columns selected; disable --no-keys/--no-values or pass --ttl")
+	if noKeys && noValues && !ttl && !showVersion && !showType && !showLabels && !showNote {
+		return ListArgs{}, fmt.Errorf("no columns selected; disable --no-keys/--no-values or pass --ttl or a --show flag")
+	}
+
+	clauses := make([]whereClause, 0, len(where))
+	for _, w := range where {
+		clause, err := parseWhere(w)
+		if err != nil {
+			return ListArgs{}, err
+		}
+		clauses = append(clauses, clause)
+	}
+	filters := make([]labelFilter, 0, len(withLabel))
+	for _, l := range withLabel {
+		f, err := parseLabelFilter(l)
+		if err != nil {
+			return ListArgs{}, err
+		}
+		filters = append(filters, f)
+	}
+	if sortBy != "key" && sortBy != "value" {
+		return ListArgs{}, fmt.Errorf("cannot sort by %q; use key or value", sortBy)
 	}
 
 	return ListArgs{
@@ -71,8 +114,76 @@ func parseFlags(cmd *cobra.Command) (ListArgs, error) {
 		key:     !noKeys,
 		value:   !noValues,
 		ttl:     ttl,
+		version: showVersion,
+		kind:    showType,
+		labels:  showLabels,
+		note:    showNote,
 		binary:  binary,
-		render:  renderFunc,
+		render:  format.renderer(),
 		secrets: secret,
+
+		where:       clauses,
+		sortByValue: sortBy == "value",
+		withLabels:  filters,
 	}, nil
 }
+
+// applyListConfig fills in list flags the user did not pass from the
+// list.format and list.columns config keys.
+func applyListConfig(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	if !flags.Changed("format") {
+		v, err := configValue("list.format")
+		if err != nil {
+			return err
+		}
+		if err := format.Set(v); err != nil {
+			return fmt.Errorf("list.format: %w", err)
+		}
+	}
+	if flags.Changed("no-keys") || flags.Changed("no-values") || flags.Changed("ttl") || flags.Changed("show-version") || flags.Changed("show-type") ||
+		flags.Changed("show-labels") || flags.Changed("show-note") {
+		return nil
+	}
+	v, err := configValue("list.columns")
+	if err != nil {
+		return err
+	}
+	columns, err := parseColumnList(v)
+	if err != nil {
+		return fmt.Errorf("list.columns: %w", err)
+	}
+	noKeys = !slices.Contains(columns, columnKey)
+	noValues = !slices.Contains(columns, columnValue)
+	ttl = slices.Contains(columns, columnTTL)
+	showVersion = slices.Contains(columns, columnVersion)
+	showType = slices.Contains(columns, columnType)
+	showLabels = slices.Contains(columns, columnLabels)
+	showNote = slices.Contains(columns, columnNote)
+	return nil
+}
+
+func parseColumnList(v string) ([]columnKind, error) {
+	var columns []columnKind
+	for _, name := range strings.Split(v, ",") {
+		switch strings.TrimSpace(name) {
+		case "key":
+			columns = append(columns, columnKey)
+		case "value":
+			columns = append(columns, columnValue)
+		case "ttl":
+			columns = append(columns, columnTTL)
+		case "version":
+			columns = append(columns, columnVersion)
+		case "type":
+			columns = append(columns, columnType)
+		case "labels":
+			columns = append(columns, columnLabels)
+		case "note":
+			columns = append(columns, columnNote)
+		default:
+			return nil, fmt.Errorf("unknown column %q; use key, value, ttl, version, type, labels or note", name)
+		}
+	}
+	return columns, nil
+}
@@ -18,6 +18,10 @@ const (
 	columnKey columnKind = iota
 	columnValue
 	columnTTL
+	columnVersion
+	columnType
+	columnLabels
+	columnNote
 )
 
 func requireColumns(args ListArgs) ([]columnKind, error) {
@@ -31,8 +35,20 @@ func requireColumns(args ListArgs) ([]columnKind, error) {
 	if args.ttl {
 		columns = append(columns, columnTTL)
 	}
+	if args.version {
+		columns = append(columns, columnVersion)
+	}
+	if args.kind {
+		columns = append(columns, columnType)
+	}
+	if args.labels {
+		columns = append(columns, columnLabels)
+	}
+	if args.note {
+		columns = append(columns, columnNote)
+	}
 	if len(columns) == 0 {
-		return nil, fmt.Errorf("no columns selected; enable key, value, or ttl output")
+		return nil, fmt.Errorf("no columns selected; enable key, value, ttl, version, type, labels or note output")
 	}
 	return columns, nil
 }
@@ -47,6 +63,14 @@ func buildHeaderCells(columnKinds []columnKind) []string {
 			labels = append(labels, "Value")
 		case columnTTL:
 			labels = append(labels, "TTL")
+		case columnVersion:
+			labels = append(labels, "Version")
+		case columnType:
+			labels = append(labels, "Type")
+		case columnLabels:
+			labels = append(labels, "Labels")
+		case columnNote:
+			labels = append(labels, "Note")
 		}
 	}
 	return labels
@@ -242,6 +266,8 @@ func basePercentageForColumn(c columnKind, hasTTL bool) float64 {
 		return 0.75
 	case columnTTL:
 		return 0.25
+	case columnVersion, columnType:
+		return 0.1
 	default:
 		return 0.25
 	}
@@ -0,0 +1,432 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	endian "encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// pushCmd represents the push command
+var pushCmd = &cobra.Command{
+	Use:   "push KEY[@DB] [VALUE...]",
+	Short: "Add values to the end of a list. Optionally specify a db.",
+	Long: `Add values to the end of a list, or to the front with --front, creating
+the list if need be. With no VALUE the value is read from Stdin.
+
+Lists make a queue for shell scripts: push adds jobs, and pop takes them
+from the front in the order they were pushed. pop --last takes from the
+end instead, for a stack.`,
+	Args:        cobra.MinimumNArgs(1),
+	Annotations: writesData,
+	RunE:        push,
+}
+
+// popCmd represents the pop command
+var popCmd = &cobra.Command{
+	Use:   "pop KEY[@DB]",
+	Short: "Remove and print the first value of a list. Optionally specify a db.",
+	Long: `Remove and print the first value of a list, or the last with --last.
+
+--wait waits up to the given time for a value if the list is empty. When
+a daemon is running it is woken as soon as a value is pushed; otherwise
+the store is checked every so often.
+
+--visibility keeps the value for the given time instead of removing it,
+and prints an id and a tab before it. Once the job is done, pass the id to
+pda ack; a value that is not acknowledged in time goes back to the front
+of the list for another pop.`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: writesData,
+	RunE:        pop,
+}
+
+// peekCmd represents the peek command
+var peekCmd = &cobra.Command{
+	Use:   "peek KEY[@DB]",
+	Short: "Print the first value of a list without removing it.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  peek,
+}
+
+// lenCmd represents the len command
+var lenCmd = &cobra.Command{
+	Use:   "len KEY[@DB]",
+	Short: "Print the number of values in a list.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  listLen,
+}
+
+// ackCmd represents the ack command
+var ackCmd = &cobra.Command{
+	Use:         "ack KEY[@DB] ID",
+	Short:       "Acknowledge a value taken with pop --visibility.",
+	Args:        cobra.ExactArgs(2),
+	Annotations: writesData,
+	RunE:        ack,
+}
+
+// Within a list, queued values are named by 'q' and their sequence number,
+// and values taken with pop --visibility by 'v' and the same number. A
+// taken value is stored with its deadline in front of it.
+const (
+	queuedElem  = 'q'
+	visibleElem = 'v'
+)
+
+func listElem(kind byte, seq uint64) []byte {
+	return endian.BigEndian.AppendUint64([]byte{kind}, seq)
+}
+
+func push(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	front, err := cmd.Flags().GetBool("front")
+	if err != nil {
+		return err
+	}
+	values := make([][]byte, 0, len(args)-1)
+	for _, v := range args[1:] {
+		values = append(values, []byte(v))
+	}
+	if len(values) == 0 {
+		v, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		values = append(values, v)
+	}
+
+	var length uint64
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: false,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			e, c, found, err := readCollection(tx, k, kindList)
+			if err != nil {
+				return err
+			}
+			for _, v := range values {
+				var seq uint64
+				if front {
+					c.head--
+					seq = c.head
+				} else {
+					seq = c.tail
+					c.tail++
+				}
+				if err := tx.Set(Entry{Key: elemKey(k, c.id, listElem(queuedElem, seq)), Value: v}); err != nil {
+					return err
+				}
+			}
+			length = c.tail - c.head
+			if err := writeCollection(tx, e, c); err != nil {
+				return err
+			}
+			return annotateSet(tx, k, !found, labelChange{})
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.ErrOrStderr(), holds(args[0], length, "value"))
+	return nil
+}
+
+func pop(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	last, err := cmd.Flags().GetBool("last")
+	if err != nil {
+		return err
+	}
+	wait, err := cmd.Flags().GetDuration("wait")
+	if err != nil {
+		return err
+	}
+	visibility, err := cmd.Flags().GetDuration("visibility")
+	if err != nil {
+		return err
+	}
+	k, dbName, err := store.parse(args[0], true)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(wait)
+	poll := 50 * time.Millisecond
+	for {
+		var value []byte
+		var seq uint64
+		var found bool
+		trans := TransactionArgs{
+			key:      args[0],
+			readonly: false,
+			sync:     false,
+			transact: func(tx Tx, k []byte) error {
+				var err error
+				value, seq, found, err = takeElem(tx, k, last, visibility)
+				return err
+			},
+		}
+		if err := store.Transaction(trans); err != nil {
+			return err
+		}
+		if found {
+			if visibility > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "%d\t", seq)
+			}
+			store.Print("%s", false, value)
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			cmd.SilenceUsage = true
+			if wait > 0 {
+				return fmt.Errorf("%q is still empty after %s", args[0], wait)
+			}
+			return fmt.Errorf("%q is empty", args[0])
+		}
+		// Re-check at least every second, for values whose visibility
+		// runs out and in case a push came just before the wait began.
+		woken, err := waitForWrite(store, dbName, k, min(remaining, time.Second))
+		if err != nil {
+			return err
+		}
+		if !woken {
+			time.Sleep(min(poll, remaining))
+			poll = min(poll*2, 500*time.Millisecond)
+		}
+	}
+}
+
+// waitForWrite waits for a write to key in a store held open by the daemon
+// or by this process's pool. A store opened by this process alone cannot
+// see other processes' writes, and keeping it open would lock them out, so
+// then it returns false at once and the caller polls.
+func waitForWrite(store *Store, dbName string, key []byte, timeout time.Duration) (bool, error) {
+	db, err := store.open(dbName)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	switch db.(type) {
+	case *remoteBackend, *pooledBackend:
+	default:
+		return false, nil
+	}
+	w, ok := capability[watcher](db)
+	if !ok {
+		return false, nil
+	}
+	return true, w.Wait(key, timeout)
+}
+
+// takeElem removes the first (or last) value of the list at key, after
+// putting back any taken values whose visibility has run out. With a
+// visibility the value is kept aside under its sequence number until then.
+func takeElem(tx Tx, key []byte, last bool, visibility time.Duration) ([]byte, uint64, bool, error) {
+	e, c, found, err := readCollection(tx, key, kindList)
+	if err != nil || !found {
+		return nil, 0, false, err
+	}
+	if err := requeueExpired(tx, key, &c); err != nil {
+		return nil, 0, false, err
+	}
+	for c.head < c.tail {
+		var seq uint64
+		if last {
+			c.tail--
+			seq = c.tail
+		} else {
+			seq = c.head
+			c.head++
+		}
+		ek := elemKey(key, c.id, listElem(queuedElem, seq))
+		v, err := tx.Get(ek)
+		if errors.Is(err, errKeyNotFound) {
+			// Gone since an undo put back the header; skip it.
+			continue
+		}
+		if err != nil {
+			return nil, 0, false, err
+		}
+		if err := tx.Delete(ek); err != nil {
+			return nil, 0, false, err
+		}
+		if visibility > 0 {
+			until := endian.BigEndian.AppendUint64(nil, uint64(time.Now().Add(visibility).UnixNano()))
+			taken := Entry{Key: elemKey(key, c.id, listElem(visibleElem, seq)), Value: append(until, v.Value...)}
+			if err := tx.Set(taken); err != nil {
+				return nil, 0, false, err
+			}
+		}
+		return v.Value, seq, true, writeCollection(tx, e, c)
+	}
+	return nil, 0, false, writeCollection(tx, e, c)
+}
+
+// requeueExpired moves taken values whose visibility has run out back to
+// the front of the list, oldest first.
+func requeueExpired(tx Tx, key []byte, c *collectionHead) error {
+	prefix := elemKey(key, c.id, []byte{visibleElem})
+	now := uint64(time.Now().UnixNano())
+	var expired []Entry
+	err := tx.Iterate(IterOptions{Prefix: prefix, Values: true}, func(e Entry) error {
+		if len(e.Value) >= 8 && endian.BigEndian.Uint64(e.Value) <= now {
+			expired = append(expired, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(expired) - 1; i >= 0; i-- {
+		c.head--
+		back := Entry{Key: elemKey(key, c.id, listElem(queuedElem, c.head)), Value: expired[i].Value[8:]}
+		if err := tx.Set(back); err != nil {
+			return err
+		}
+		if err := tx.Delete(expired[i].Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func peek(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	last, err := cmd.Flags().GetBool("last")
+	if err != nil {
+		return err
+	}
+	var value []byte
+	var found bool
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: true,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			_, c, ok, err := readCollection(tx, k, kindList)
+			if err != nil || !ok {
+				return err
+			}
+			for c.head < c.tail {
+				seq := c.head
+				if last {
+					seq = c.tail - 1
+				}
+				e, err := tx.Get(elemKey(k, c.id, listElem(queuedElem, seq)))
+				if err == nil {
+					value, found = e.Value, true
+					return nil
+				}
+				if !errors.Is(err, errKeyNotFound) {
+					return err
+				}
+				if last {
+					c.tail--
+				} else {
+					c.head++
+				}
+			}
+			return nil
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%q is empty", args[0])
+	}
+	store.Print("%s", false, value)
+	return nil
+}
+
+func listLen(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	var length uint64
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: true,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			_, c, _, err := readCollection(tx, k, kindList)
+			length = c.tail - c.head
+			return err
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), length)
+	return nil
+}
+
+func ack(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	seq, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad id %q", args[1])
+	}
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: false,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			_, c, found, err := readCollection(tx, k, kindList)
+			if err != nil {
+				return err
+			}
+			ek := elemKey(k, c.id, listElem(visibleElem, seq))
+			if !found {
+				return fmt.Errorf("%q does not exist", args[0])
+			}
+			taken, err := tx.Get(ek)
+			if errors.Is(err, errKeyNotFound) {
+				return fmt.Errorf("%q has no value %d waiting to be acknowledged", args[0], seq)
+			}
+			if err != nil {
+				return err
+			}
+			if len(taken.Value) < 8 || endian.BigEndian.Uint64(taken.Value) <= uint64(time.Now().UnixNano()) {
+				return fmt.Errorf("value %d of %q was not acknowledged in time and is back in the list", seq, args[0])
+			}
+			return tx.Delete(ek)
+		},
+	}
+	return store.Transaction(trans)
+}
+
+func init() {
+	pushCmd.Flags().Bool("front", false, "add the values to the front of the list")
+	popCmd.Flags().Bool("last", false, "take the last value instead of the first, as from a stack")
+	popCmd.Flags().Duration("wait", 0, "wait up to this long for a value if the list is empty")
+	popCmd.Flags().Duration("visibility", 0, "put the value back unless it is acknowledged with pda ack within this time")
+	peekCmd.Flags().Bool("last", false, "print the last value instead of the first")
+	rootCmd.AddCommand(pushCmd, popCmd, peekCmd, lenCmd, ackCmd)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/spf13/cobra"
+)
+
+// rekeyCmd represents the rekey command
+var rekeyCmd = &cobra.Command{
+	Use:         "rekey DB",
+	Short:       "Change the key of an encrypted database and rotate its data key.",
+	Args:        cobra.ExactArgs(1),
+	Annotations: writesData,
+	RunE:        rekey,
+}
+
+func rekey(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	path, err := store.FindStore(args[0])
+	if err != nil {
+		var notFound errNotFound
+		if errors.As(err, &notFound) {
+			return fmt.Errorf("%q does not exist, %s", args[0], err.Error())
+		}
+		return err
+	}
+	meta, err := readStoreMeta(path)
+	if err != nil {
+		return err
+	}
+	if !meta.Encrypted {
+		return fmt.Errorf("%q is not encrypted; create it with create-db --encrypt", args[0])
+	}
+
+	material, err := loadKeyMaterial()
+	if err != nil {
+		return err
+	}
+	newKeyfile, err := cmd.Flags().GetString("new-keyfile")
+	if err != nil {
+		return err
+	}
+	newMaterial, err := readKeyMaterial(newKeyfile, os.Getenv("PDA_NEW_PASSPHRASE"), "New passphrase: ")
+	if err != nil {
+		return err
+	}
+	newSaltBytes, err := newSalt()
+	if err != nil {
+		return err
+	}
+
+	if err := releaseStore(path); err != nil {
+		return err
+	}
+	timeout, err := store.lockTimeout()
+	if err != nil {
+		return err
+	}
+	unlock, err := waitLockStore(args[0], path, timeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = rekeyStore(path, meta, deriveKey(material, meta.Salt), deriveKey(newMaterial, newSaltBytes), newSaltBytes)
+	if errors.Is(err, badger.ErrEncryptionKeyMismatch) {
+		return fmt.Errorf("cannot open %q; %w", args[0], errWrongKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Rekeyed %q\n", args[0])
+	return nil
+}
+
+// writeKeyRegistry is badger.WriteKeyRegistry, swapped out by tests.
+var writeKeyRegistry = badger.WriteKeyRegistry
+
+// rekeyStore re-encrypts the key registry of the store at path under newKey
+// and records newSalt in its meta. The new meta is staged before the registry
+// is rewritten and renamed into place after, and a failure in between puts
+// the old registry back, so the stored salt always derives the key the
+// registry is encrypted under. The caller holds the store lock.
+func rekeyStore(path string, meta storeMeta, oldKey, newKey, newSalt []byte) error {
+	oldOpts := badger.KeyRegistryOptions{
+		Dir:           path,
+		ReadOnly:      true,
+		EncryptionKey: oldKey,
+	}
+	reg, err := badger.OpenKeyRegistry(oldOpts)
+	if err != nil {
+		return err
+	}
+	regPath := filepath.Join(path, badger.KeyRegistryFileName)
+	oldReg, err := os.ReadFile(regPath)
+	if err != nil {
+		reg.Close()
+		return err
+	}
+
+	meta.Salt = newSalt
+	staged, err := stageStoreMeta(path, meta)
+	if err != nil {
+		reg.Close()
+		return err
+	}
+	newOpts := badger.KeyRegistryOptions{
+		Dir:                           path,
+		EncryptionKey:                 newKey,
+		EncryptionKeyRotationDuration: 0,
+	}
+	err = writeKeyRegistry(reg, newOpts)
+	if closeErr := reg.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(staged)
+		return errors.Join(err, restoreKeyRegistry(regPath, oldReg))
+	}
+	if err := os.Rename(staged, filepath.Join(path, storeMetaFile)); err != nil {
+		os.Remove(staged)
+		return errors.Join(err, restoreKeyRegistry(regPath, oldReg))
+	}
+
+	// A zero rotation duration makes the registry mint a fresh data key, which
+	// badger uses for everything written from now on.
+	reg, err = badger.OpenKeyRegistry(newOpts)
+	if err != nil {
+		return err
+	}
+	if _, err := reg.LatestDataKey(); err != nil {
+		reg.Close()
+		return err
+	}
+	return reg.Close()
+}
+
+// restoreKeyRegistry puts back the registry a failed rekey replaced.
+func restoreKeyRegistry(regPath string, old []byte) error {
+	tmp := regPath + ".restore"
+	if err := os.WriteFile(tmp, old, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, regPath)
+}
+
+func init() {
+	rekeyCmd.Flags().String("new-keyfile", "", "path to the new key (or set PDA_NEW_PASSPHRASE)")
+	rootCmd.AddCommand(rekeyCmd)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// newEncryptedStore creates an encrypted badger store holding k=v and
+// returns its directory, meta and key.
+func newEncryptedStore(t *testing.T) (string, storeMeta, []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	salt, err := newSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := storeMeta{Encrypted: true, Salt: salt}
+	if err := writeStoreMeta(dir, meta); err != nil {
+		t.Fatal(err)
+	}
+	key := deriveKey([]byte("old"), salt)
+	db, err := openBadger(dir, meta, key, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, _ := db.NewTx(true)
+	if err := tx.Set(Entry{Key: []byte("k"), Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir, meta, key
+}
+
+// readWith opens the store with the key derived from material and its
+// stored salt, and returns the value of k.
+func readWith(dir string, material []byte) ([]byte, error) {
+	meta, err := readStoreMeta(dir)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openBadger(dir, meta, deriveKey(material, meta.Salt), true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	tx, _ := db.NewTx(false)
+	defer tx.Discard()
+	e, err := tx.Get([]byte("k"))
+	return e.Value, err
+}
+
+func TestRekeyStore(t *testing.T) {
+	errWrite := errors.New("disk full")
+	tests := []struct {
+		name    string
+		write   func(*badger.KeyRegistry, badger.KeyRegistryOptions) error
+		wantErr error
+		opens   string
+	}{
+		{"succeeds", badger.WriteKeyRegistry, nil, "new"},
+		{"registry write fails", func(*badger.KeyRegistry, badger.KeyRegistryOptions) error {
+			return errWrite
+		}, errWrite, "old"},
+		{"registry written then fails", func(reg *badger.KeyRegistry, opts badger.KeyRegistryOptions) error {
+			if err := badger.WriteKeyRegistry(reg, opts); err != nil {
+				return err
+			}
+			return errWrite
+		}, errWrite, "old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, meta, key := newEncryptedStore(t)
+			writeKeyRegistry = tt.write
+			defer func() { writeKeyRegistry = badger.WriteKeyRegistry }()
+
+			newSaltBytes, err := newSalt()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = rekeyStore(dir, meta, key, deriveKey([]byte("new"), newSaltBytes), newSaltBytes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("rekeyStore() = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(dir, storeMetaFile+".tmp")); !os.IsNotExist(err) {
+				t.Errorf("staged meta left behind: %v", err)
+			}
+			v, err := readWith(dir, []byte(tt.opens))
+			if err != nil {
+				t.Fatalf("open with %s key: %v", tt.opens, err)
+			}
+			if !bytes.Equal(v, []byte("v")) {
+				t.Errorf("k = %q, want %q", v, "v")
+			}
+			other := map[string]string{"old": "new", "new": "old"}[tt.opens]
+			if _, err := readWith(dir, []byte(other)); err == nil {
+				t.Errorf("store still opens with %s key", other)
+			}
+		})
+	}
+}
+
+func TestWaitLockStoreExcludesOpen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("badger does not flock the store directory on windows")
+	}
+	dir, meta, key := newEncryptedStore(t)
+	unlock, err := waitLockStore("test", dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openBadger(dir, meta, key, false); !errors.Is(err, errLocked) {
+		t.Errorf("open while locked = %v, want errLocked", err)
+	}
+	if _, err := waitLockStore("test", dir, 0); !errors.As(err, new(errLockTimeout)) {
+		t.Errorf("second lock = %v, want errLockTimeout", err)
+	}
+	unlock()
+	db, err := openBadger(dir, meta, key, false)
+	if err != nil {
+		t.Fatalf("open after unlock: %v", err)
+	}
+	db.Close()
+}
@@ -3,26 +3,28 @@ package cmd
 import (
 	"bufio"
 	"encoding/base64"
+	endian "encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 
-	"github.com/dgraph-io/badger/v4"
 	"github.com/spf13/cobra"
 )
 
 var restoreCmd = &cobra.Command{
-	Use:   "restore [DB]",
-	Short: "Restore key/value pairs from an NDJSON dump",
-	Args:  cobra.MaximumNArgs(1),
-	RunE:  restore,
+	Use:         "restore [DB]",
+	Short:       "Restore key/value pairs from an NDJSON or badger dump",
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: writesData,
+	RunE:        restore,
 }
 
 func restore(cmd *cobra.Command, args []string) error {
 	store := &Store{}
-	dbName := "default"
+	dbName := defaultDB()
 	if len(args) == 1 {
 		parsed, err := store.parseDB(args[0], false)
 		if err != nil {
@@ -39,21 +41,50 @@ func restore(cmd *cobra.Command, args []string) error {
 		defer closer.Close()
 	}
 
+	noSnapshot, err := cmd.Flags().GetBool("no-snapshot")
+	if err != nil {
+		return err
+	}
+	if !noSnapshot {
+		if err := safetySnapshot(cmd, store, dbName, "before-restore"); err != nil {
+			return err
+		}
+	}
+
 	db, err := store.open(dbName)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	scanner := bufio.NewScanner(reader)
+	br := bufio.NewReader(reader)
+	badgerDump, err := isBadgerDump(br)
+	if err != nil {
+		return err
+	}
+	if badgerDump {
+		s, ok := capability[snapshotter](db)
+		if !ok {
+			return errNoSnapshots
+		}
+		if err := s.Load(br); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Loaded badger dump into @%s\n", dbName)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(br)
 	buf := make([]byte, 1024*1024)
 	scanner.Buffer(buf, 8*1024*1024)
 
-	wb := db.NewWriteBatch()
+	wb := db.NewBatch()
 	defer wb.Cancel()
 
 	lineNo := 0
 	var restored int
+	var stale [][]byte
+	keys := newKeyring()
 
 	for scanner.Scan() {
 		lineNo++
@@ -70,17 +101,47 @@ func restore(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("line %d: missing key", lineNo)
 		}
 
+		leftover, err := staleKeys(db, entry)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		stale = append(stale, leftover...)
+		if err := restoreAnnotation(wb, entry); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if entry.Type != "" && entry.Type != "string" {
+			if err := restoreCollection(wb, entry); err != nil {
+				return fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			restored++
+			continue
+		}
+
 		value, err := decodeEntryValue(entry)
 		if err != nil {
 			return fmt.Errorf("line %d: %w", lineNo, err)
 		}
 
 		entryMeta := byte(0x0)
+		if entry.ValueType != "" {
+			t, err := parseValueType(entry.ValueType)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			if err := t.validate(value); err != nil {
+				return fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			entryMeta = t.meta()
+		}
 		if entry.Secret {
-			entryMeta = metaSecret
+			value, err = keys.seal(value)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			entryMeta |= metaSecret | metaEncrypted
 		}
 
-		writeEntry := badger.NewEntry([]byte(entry.Key), value).WithMeta(entryMeta)
+		writeEntry := Entry{Key: []byte(entry.Key), Value: value, Meta: entryMeta}
 		if entry.ExpiresAt != nil {
 			if *entry.ExpiresAt < 0 {
 				return fmt.Errorf("line %d: expires_at must be >= 0", lineNo)
@@ -88,7 +149,7 @@ func restore(cmd *cobra.Command, args []string) error {
 			writeEntry.ExpiresAt = uint64(*entry.ExpiresAt)
 		}
 
-		if err := wb.SetEntry(writeEntry); err != nil {
+		if err := wb.Set(writeEntry); err != nil {
 			return fmt.Errorf("line %d: %w", lineNo, err)
 		}
 		restored++
@@ -101,9 +162,86 @@ func restore(cmd *cobra.Command, args []string) error {
 	if err := wb.Flush(); err != nil {
 		return err
 	}
+	if err := deleteKeys(db, stale); err != nil {
+		return err
+	}
 
 	fmt.Fprintf(cmd.ErrOrStderr(), "Restored %d entries into @%s\n", restored, dbName)
-	return nil
+	return autoGC(cmd, db, dbName, restored)
+}
+
+// isBadgerDump reports whether r holds a badger backup stream rather than
+// NDJSON, and fails if it holds neither. A badger stream is a run of
+// protobuf KV lists, each after its little-endian uint64 length; the length
+// is non-zero with zero high bytes, and a KV list opens with the tag of its
+// first field. A JSON line never contains a zero byte and opens with {.
+func isBadgerDump(r *bufio.Reader) (bool, error) {
+	head, _ := r.Peek(9)
+	if len(head) == 9 {
+		size := endian.LittleEndian.Uint64(head)
+		if size > 0 && size < 1<<32 && head[8] == badgerKVListTag {
+			return true, nil
+		}
+	}
+	for i := 0; ; i++ {
+		b, err := r.Peek(i + 1)
+		if err != nil {
+			// Empty or blank input restores nothing.
+			return false, nil
+		}
+		switch b[i] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{':
+			return false, nil
+		}
+		return false, errors.New("input is neither an NDJSON nor a badger dump")
+	}
+}
+
+// badgerKVListTag is the protobuf tag of the repeated kv field that starts
+// every KV list in a badger backup.
+const badgerKVListTag = 1<<3 | 2
+
+// staleKeys returns the keys that restoring entry leaves behind: the
+// elements of the collection at its key, if there is one, and its labels
+// and note if entry has none. A restored collection gets a new id and a
+// restored string has none, so without deleting these the old elements
+// would stay behind until gc and the old labels would carry over. Restore
+// deletes them only once its batch is flushed, so that a restore that
+// fails leaves the old key whole.
+func staleKeys(db Backend, entry dumpEntry) ([][]byte, error) {
+	tx, err := db.NewTx(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Discard()
+	key := []byte(entry.Key)
+	var stale [][]byte
+	if (annotation{Labels: entry.Labels, Note: entry.Note}).empty() {
+		_, err := tx.Get(annotationKey(key))
+		if err == nil {
+			stale = append(stale, annotationKey(key))
+		} else if !errors.Is(err, errKeyNotFound) {
+			return nil, err
+		}
+	}
+	e, err := tx.Get(key)
+	if errors.Is(err, errKeyNotFound) || (err == nil && e.Meta&metaKind == 0) {
+		return stale, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := decodeCollectionHead(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Iterate(IterOptions{Prefix: elemKey(key, c.id, nil)}, func(e Entry) error {
+		stale = append(stale, e.Key)
+		return nil
+	})
+	return stale, err
 }
 
 func restoreInput(cmd *cobra.Command) (io.Reader, io.Closer, error) {
@@ -122,21 +260,105 @@ func restoreInput(cmd *cobra.Command) (io.Reader, io.Closer, error) {
 }
 
 func decodeEntryValue(entry dumpEntry) ([]byte, error) {
-	switch entry.Encoding {
+	return decodeDumpValue(entry.Encoding, entry.Value)
+}
+
+func decodeDumpValue(encoding, v string) ([]byte, error) {
+	switch encoding {
 	case "", "text":
-		return []byte(entry.Value), nil
+		return []byte(v), nil
 	case "base64":
-		b, err := base64.StdEncoding.DecodeString(entry.Value)
+		b, err := base64.StdEncoding.DecodeString(v)
 		if err != nil {
 			return nil, err
 		}
 		return b, nil
 	default:
-		return nil, fmt.Errorf("unsupported encoding %q", entry.Encoding)
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+// restoreAnnotation writes the labels and note of a dumped key. A batch
+// cannot delete, so those of a key dumped without any are left to
+// staleKeys.
+func restoreAnnotation(wb Batch, entry dumpEntry) error {
+	a := annotation{Labels: entry.Labels, Note: entry.Note}
+	if a.empty() {
+		return nil
+	}
+	for name := range a.Labels {
+		if name == "" || strings.ContainsAny(name, ",= ") {
+			return fmt.Errorf("bad label name %q", name)
+		}
+	}
+	v, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return wb.Set(Entry{Key: annotationKey([]byte(entry.Key)), Value: v})
+}
+
+// restoreCollection writes a list, hash or set from a dump as a new
+// collection, replacing whatever was at its key.
+func restoreCollection(wb Batch, entry dumpEntry) error {
+	var kind byte
+	switch entry.Type {
+	case "list":
+		kind = kindList
+	case "hash":
+		kind = kindHash
+	case "set":
+		kind = kindSet
+	default:
+		return fmt.Errorf("unsupported type %q", entry.Type)
+	}
+	key := []byte(entry.Key)
+	c := newCollectionHead()
+	// A hand-written dump may repeat a set member; it is stored once, so it
+	// is counted once.
+	seen := map[string]bool{}
+	for _, item := range entry.Items {
+		v, err := decodeDumpValue(entry.Encoding, item)
+		if err != nil {
+			return err
+		}
+		if kind == kindSet {
+			if seen[string(v)] {
+				continue
+			}
+			seen[string(v)] = true
+		}
+		elem := Entry{Key: elemKey(key, c.id, v)}
+		if kind == kindList {
+			elem = Entry{Key: elemKey(key, c.id, listElem(queuedElem, c.tail)), Value: v}
+		}
+		if err := wb.Set(elem); err != nil {
+			return err
+		}
+		c.tail++
+	}
+	for field, item := range entry.Fields {
+		v, err := decodeDumpValue(entry.Encoding, item)
+		if err != nil {
+			return err
+		}
+		if err := wb.Set(Entry{Key: elemKey(key, c.id, []byte(field)), Value: v}); err != nil {
+			return err
+		}
+		c.tail++
+	}
+	head := Entry{Key: key, Value: c.encode(), Meta: kind}
+	if entry.ExpiresAt != nil {
+		if *entry.ExpiresAt < 0 {
+			return fmt.Errorf("expires_at must be >= 0")
+		}
+		head.ExpiresAt = uint64(*entry.ExpiresAt)
 	}
+	return wb.Set(head)
 }
 
 func init() {
 	restoreCmd.Flags().StringP("file", "f", "", "Path to an NDJSON dump (defaults to stdin)")
+	restoreCmd.Flags().Bool("no-snapshot", false, "Do not snapshot a db that has keys before restoring into it")
 	rootCmd.AddCommand(restoreCmd)
 }
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// countElems returns how many collection elements db holds, reachable or
+// not.
+func countElems(t *testing.T, db Backend) int {
+	t.Helper()
+	tx, _ := db.NewTx(false)
+	defer tx.Discard()
+	n := 0
+	err := tx.Iterate(IterOptions{Prefix: []byte(elemPrefix)}, func(e Entry) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// restoreEntry restores one dump entry the way pda restore does.
+func restoreEntry(t *testing.T, db Backend, entry dumpEntry) {
+	t.Helper()
+	stale, err := staleKeys(db, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wb := db.NewBatch()
+	if err := restoreAnnotation(wb, entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Type == "" || entry.Type == "string" {
+		err = wb.Set(Entry{Key: []byte(entry.Key), Value: []byte(entry.Value)})
+	} else {
+		err = restoreCollection(wb, entry)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteKeys(db, stale); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestoreCollectionReplacesElements(t *testing.T) {
+	list := dumpEntry{Key: "c", Type: "list", Items: []string{"a", "b", "c"}}
+	hash := dumpEntry{Key: "c", Type: "hash", Fields: map[string]string{"x": "1", "y": "2"}}
+	set := dumpEntry{Key: "c", Type: "set", Items: []string{"m"}}
+	str := dumpEntry{Key: "c", Value: "plain"}
+	tests := []struct {
+		name      string
+		old, next dumpEntry
+		want      int
+	}{
+		{"list over list", list, list, 3},
+		{"hash over list", list, hash, 2},
+		{"set over hash", hash, set, 1},
+		{"list over set", set, list, 3},
+		{"string over list", list, str, 0},
+		{"list over string", str, list, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := openBadgerMemory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			other := dumpEntry{Key: "other", Type: "set", Items: []string{"keep", "me"}}
+			restoreEntry(t, db, other)
+			restoreEntry(t, db, tt.old)
+			restoreEntry(t, db, tt.next)
+			if got := countElems(t, db); got != tt.want+2 {
+				t.Errorf("store holds %d elements, want %d", got, tt.want+2)
+			}
+		})
+	}
+}
+
+func TestRestoreCollectionCountsSetMembersOnce(t *testing.T) {
+	db, err := openBadgerMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	restoreEntry(t, db, dumpEntry{Key: "s", Type: "set", Items: []string{"m", "n", "m"}})
+	tx, _ := db.NewTx(false)
+	defer tx.Discard()
+	_, c, _, err := readCollection(tx, []byte("s"), kindSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := c.tail - c.head; size != 2 || countElems(t, db) != 2 {
+		t.Errorf("set holds %d members in %d elements, want 2", size, countElems(t, db))
+	}
+}
+
+func TestRestoreReplacesAnnotation(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry dumpEntry
+		want  string
+	}{
+		{"without labels", dumpEntry{Key: "k", Value: "v"}, ""},
+		{"with a note", dumpEntry{Key: "k", Value: "v", Note: "new"}, "new"},
+		{"collection without labels", dumpEntry{Key: "k", Type: "set", Items: []string{"m"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := openBadgerMemory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			restoreEntry(t, db, dumpEntry{Key: "k", Value: "old", Note: "old", Labels: map[string]string{"a": "1"}})
+			restoreEntry(t, db, tt.entry)
+			tx, _ := db.NewTx(false)
+			defer tx.Discard()
+			a, err := readAnnotation(tx, []byte("k"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Note != tt.want || len(a.Labels) != 0 {
+				t.Errorf("annotation = %+v, want note %q and no labels", a, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBadgerDump(t *testing.T) {
+	db, err := openBadgerMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, _ := db.NewTx(true)
+	if err := tx.Set(Entry{Key: []byte("k"), Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var backup bytes.Buffer
+	if _, err := db.Backup(&backup, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    bool
+		wantErr bool
+	}{
+		{"badger backup", backup.String(), true, false},
+		{"ndjson", `{"key":"k","value":"v"}` + "\n", false, false},
+		{"ndjson after blank lines", "\n  \n{\"key\":\"k\"}\n", false, false},
+		{"empty", "", false, false},
+		{"zero length prefix", "\x00\x00\x00\x00\x00\x00\x00\x00\x0a", false, true},
+		{"zero high bytes without a kv list", "\x05\x00\x00\x00\x00\x00\x00\x00xxxxx", false, true},
+		{"csv", "key,value\nk,v\n", false, true},
+	}
+	for _, tt := range tests {
+		got, err := isBadgerDump(bufio.NewReader(strings.NewReader(tt.in)))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: isBadgerDump() = %v, %v; want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
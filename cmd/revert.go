@@ -0,0 +1,76 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// revertCmd represents the revert command
+var revertCmd = &cobra.Command{
+	Use:   "revert KEY[@DB]",
+	Short: "Set a key back to an earlier version.",
+	Long: `Set a key back to an earlier version, chosen with --version or --at.
+
+The old value is written as a new version, keeping its secret flag and, if
+it has not passed, its expiry. Reverting to a deletion deletes the key.`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: writesData,
+	RunE:        revert,
+}
+
+func revert(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	rev, err := findRevision(cmd, store, args[0])
+	if err != nil {
+		return err
+	}
+
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: false,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			if rev.Deleted {
+				return tx.Delete(k)
+			}
+			e := Entry{Key: k, Value: rev.Value, Meta: rev.Meta}
+			if !expired(rev.ExpiresAt) {
+				e.ExpiresAt = rev.ExpiresAt
+			}
+			return tx.Set(e)
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Reverted %q to version %d\n", args[0], rev.Version)
+	return nil
+}
+
+func init() {
+	revertCmd.Flags().Uint64("version", 0, "version to go back to, from pda history")
+	revertCmd.Flags().String("at", "", "go back to the version current at this time")
+	rootCmd.AddCommand(revertCmd)
+}
@@ -22,11 +22,35 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"runtime/debug"
 
 	"github.com/spf13/cobra"
 )
 
+// annotationWrites marks commands that change stored data or settings, which
+// --read-only refuses to run.
+const annotationWrites = "pda:writes"
+
+var writesData = map[string]string{annotationWrites: "true"}
+
+// version is set for release builds with
+// -ldflags "-X github.com/llywelwyn/pda/cmd.version=v1.2.3".
+var version string
+
+// pdaVersion returns version, or the module version for go install builds.
+func pdaVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "pda",
@@ -39,13 +63,50 @@ var rootCmd = &cobra.Command{
  ███▄▄██▀  ▀██▄▄███  ██▄▄▄███
  ██ ▀▀▀      ▀▀▀ ▀▀   ▀▀▀▀ ▀▀
  ██      (c) 2025 Lewis Wynne
-`}
+`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if readOnlyFlag && cmd.Annotations[annotationWrites] == "true" {
+			return fmt.Errorf("%s changes data; refusing with --read-only", cmd.CommandPath())
+		}
+		// The config commands are how a broken config file gets fixed, so
+		// they load it themselves, if at all.
+		if isConfigCmd(cmd) {
+			return nil
+		}
+		_, err := currentConfig()
+		return err
+	},
+}
+
+// exitPrecondition is the exit status of a conditional write that did not
+// happen, so that scripts can tell it apart from other failures.
+const exitPrecondition = 3
 
+// Execute runs pda and exits with exitCode of its error. Commands return
+// errors rather than exiting themselves, so deferred closes always run.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	if code := exitCode(rootCmd.Execute()); code != 0 {
+		os.Exit(code)
 	}
 }
 
-func init() {}
+// exitCode is the exit status for err: 0 for nil, the ExitCode of an error
+// that has one, such as preconditionError, and 1 otherwise.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var coded interface{ ExitCode() int }
+	if errors.As(err, &coded) && coded.ExitCode() > 0 {
+		return coded.ExitCode()
+	}
+	return 1
+}
+
+func init() {
+	rootCmd.Version = pdaVersion()
+	rootCmd.PersistentFlags().DurationVar(&lockTimeoutFlag, "lock-timeout", 0, "how long to wait for another pda process to release a store (default from lock.timeout, 10s)")
+	rootCmd.PersistentFlags().StringVar(&storeDir, "store-dir", "", "use only the stores in this directory (overrides PDA_HOME and .pda/)")
+	rootCmd.PersistentFlags().BoolVar(&readOnlyFlag, "read-only", false, "open stores read-only and refuse any command that changes data (readers still lock out writers unless pda daemon is running)")
+	rootCmd.PersistentFlags().StringVar(&keyfile, "keyfile", "", "path to the key used to encrypt secret values (or set PDA_KEYFILE/PDA_PASSPHRASE)")
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	endian "encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// sealChunkSize is how much plaintext each frame of a sealed stream holds.
+const sealChunkSize = 64 << 10
+
+// A sealed stream is a run of frames, each a final flag byte, the
+// big-endian length of its ciphertext, a nonce and the ciphertext of up to
+// sealChunkSize bytes sealed with AES-256-GCM. The flag is authenticated
+// too, so a stream cut short between frames is caught.
+type sealWriter struct {
+	w    io.Writer
+	gcm  cipher.AEAD
+	buf  []byte
+	done bool
+}
+
+// newSealWriter encrypts everything written to it into w under key. Close
+// writes the final frame and must be called.
+func newSealWriter(w io.Writer, key []byte) (*sealWriter, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &sealWriter{w: w, gcm: gcm, buf: make([]byte, 0, sealChunkSize)}, nil
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if len(s.buf) == sealChunkSize {
+			if err := s.frame(false); err != nil {
+				return n, err
+			}
+		}
+		c := copy(s.buf[len(s.buf):sealChunkSize], p)
+		s.buf = s.buf[:len(s.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+func (s *sealWriter) Close() error {
+	if s.done {
+		return nil
+	}
+	s.done = true
+	return s.frame(true)
+}
+
+func (s *sealWriter) frame(final bool) error {
+	flag := []byte{0}
+	if final {
+		flag[0] = 1
+	}
+	nonce := make([]byte, s.gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := s.gcm.Seal(nil, nonce, s.buf, flag)
+	s.buf = s.buf[:0]
+	head := append(flag, endian.BigEndian.AppendUint32(nil, uint32(len(sealed)))...)
+	for _, b := range [][]byte{head, nonce, sealed} {
+		if _, err := s.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealReader decrypts a stream written by a sealWriter.
+type sealReader struct {
+	r    *bufio.Reader
+	gcm  cipher.AEAD
+	buf  []byte
+	done bool
+}
+
+func newSealReader(r io.Reader, key []byte) (*sealReader, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &sealReader{r: bufio.NewReader(r), gcm: gcm}, nil
+}
+
+var errSealTruncated = errors.New("encrypted stream is truncated")
+
+func (s *sealReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *sealReader) next() error {
+	head := make([]byte, 5+s.gcm.NonceSize())
+	if _, err := io.ReadFull(s.r, head); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return errSealTruncated
+		}
+		return err
+	}
+	size := endian.BigEndian.Uint32(head[1:5])
+	if head[0] > 1 || size > sealChunkSize+uint32(s.gcm.Overhead()) {
+		return fmt.Errorf("corrupt encrypted stream")
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(s.r, sealed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return errSealTruncated
+		}
+		return err
+	}
+	plain, err := s.gcm.Open(nil, head[5:], sealed, head[:1])
+	if err != nil {
+		return errWrongKey
+	}
+	s.buf = plain
+	s.done = head[0] == 1
+	return nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSealStream(t *testing.T) {
+	key := deriveKey([]byte("pass"), []byte("0123456789abcdef"))
+	big := make([]byte, 3*sealChunkSize+17)
+	rand.Read(big)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short", []byte("hello")},
+		{"exactly one chunk", big[:sealChunkSize]},
+		{"several chunks", big},
+	}
+	for _, tt := range tests {
+		var sealed bytes.Buffer
+		sw, err := newSealWriter(&sealed, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sw.Write(tt.data); err != nil {
+			t.Fatal(err)
+		}
+		if err := sw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(tt.data) > 4 && bytes.Contains(sealed.Bytes(), tt.data) {
+			t.Errorf("%s: plaintext visible in sealed stream", tt.name)
+		}
+
+		sr, _ := newSealReader(bytes.NewReader(sealed.Bytes()), key)
+		got, err := io.ReadAll(sr)
+		if err != nil || !bytes.Equal(got, tt.data) {
+			t.Errorf("%s: round trip = %d bytes, %v", tt.name, len(got), err)
+		}
+
+		other := deriveKey([]byte("other"), []byte("0123456789abcdef"))
+		sr, _ = newSealReader(bytes.NewReader(sealed.Bytes()), other)
+		if _, err := io.ReadAll(sr); !errors.Is(err, errWrongKey) {
+			t.Errorf("%s: wrong key = %v, want errWrongKey", tt.name, err)
+		}
+
+		sr, _ = newSealReader(bytes.NewReader(sealed.Bytes()[:sealed.Len()-1]), key)
+		if _, err := io.ReadAll(sr); err == nil {
+			t.Errorf("%s: truncated stream read without error", tt.name)
+		}
+	}
+}
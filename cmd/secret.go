@@ -0,0 +1,231 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+const (
+	sealVersion   byte = 0x1
+	sealSaltSize       = 16
+	sealKeySize        = 32
+	argonTime          = 1
+	argonMemory        = 64 * 1024
+	argonThreads       = 4
+	envKeyfile         = "PDA_KEYFILE"
+	envPassphrase      = "PDA_PASSPHRASE"
+)
+
+var (
+	errNoSecretKey = errors.New("no secret key available; pass --keyfile or set PDA_KEYFILE or PDA_PASSPHRASE")
+	errWrongKey    = errors.New("wrong passphrase or keyfile")
+	keyfile        string
+)
+
+// keyMaterial caches the user's keyfile or passphrase. It is guarded by
+// keyMaterialMu because serve opens stores from concurrent requests.
+// noKeyPrompt is set by serve so that a request never prompts on the
+// server's terminal.
+var (
+	keyMaterialMu sync.Mutex
+	keyMaterial   []byte
+	noKeyPrompt   bool
+)
+
+// keyring derives and caches the AES keys used for secret values and
+// encrypted stores, one per salt.
+type keyring struct {
+	salt []byte
+	keys map[string][]byte
+}
+
+func newKeyring() *keyring {
+	return &keyring{keys: map[string][]byte{}}
+}
+
+// loadKeyMaterial reads the user's keyfile or passphrase once per process.
+func loadKeyMaterial() ([]byte, error) {
+	keyMaterialMu.Lock()
+	defer keyMaterialMu.Unlock()
+	if keyMaterial != nil {
+		return keyMaterial, nil
+	}
+	path := keyfile
+	if strings.TrimSpace(path) == "" {
+		path = os.Getenv(envKeyfile)
+	}
+	prompt := "Passphrase: "
+	if noKeyPrompt {
+		prompt = ""
+	}
+	material, err := readKeyMaterial(path, os.Getenv(envPassphrase), prompt)
+	if err != nil {
+		return nil, err
+	}
+	keyMaterial = material
+	return material, nil
+}
+
+// readKeyMaterial reads a keyfile, falling back to passphrase and then to
+// prompting on the terminal. An empty prompt disables prompting.
+func readKeyMaterial(path, passphrase, prompt string) ([]byte, error) {
+	if strings.TrimSpace(path) != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read keyfile: %w", err)
+		}
+		if len(b) == 0 {
+			return nil, fmt.Errorf("keyfile %q is empty", path)
+		}
+		return b, nil
+	}
+	if passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	if prompt == "" || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, errNoSecretKey
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errNoSecretKey
+	}
+	return pass, nil
+}
+
+// disableKeyPrompt stops later loadKeyMaterial calls from prompting.
+func disableKeyPrompt() {
+	keyMaterialMu.Lock()
+	defer keyMaterialMu.Unlock()
+	noKeyPrompt = true
+}
+
+func (kr *keyring) derive(salt []byte) ([]byte, error) {
+	if key, ok := kr.keys[string(salt)]; ok {
+		return key, nil
+	}
+	material, err := loadKeyMaterial()
+	if err != nil {
+		return nil, err
+	}
+	key := deriveKey(material, salt)
+	kr.keys[string(salt)] = key
+	return key, nil
+}
+
+func deriveKey(material, salt []byte) []byte {
+	return argon2.IDKey(material, salt, argonTime, argonMemory, argonThreads, sealKeySize)
+}
+
+// seal encrypts v with AES-256-GCM. The output is laid out as
+// version | salt | nonce | ciphertext.
+func (kr *keyring) seal(v []byte) ([]byte, error) {
+	if kr.salt == nil {
+		salt, err := newSalt()
+		if err != nil {
+			return nil, err
+		}
+		kr.salt = salt
+	}
+	key, err := kr.derive(kr.salt)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, 1+len(kr.salt)+len(nonce)+len(v)+gcm.Overhead())
+	out = append(out, sealVersion)
+	out = append(out, kr.salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, v, nil), nil
+}
+
+func (kr *keyring) open(key string, v []byte) ([]byte, error) {
+	if len(v) < 1+sealSaltSize || v[0] != sealVersion {
+		return nil, fmt.Errorf("%q has an unrecognised encrypted value", key)
+	}
+	salt := v[1 : 1+sealSaltSize]
+	k, err := kr.derive(salt)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+	rest := v[1+sealSaltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%q has a truncated encrypted value", key)
+	}
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %q; wrong passphrase or keyfile", key)
+	}
+	return plain, nil
+}
+
+// reveal returns the plaintext of a stored value, decrypting it if its meta
+// marks it as encrypted.
+func (kr *keyring) reveal(key string, meta byte, v []byte) ([]byte, error) {
+	if meta&metaEncrypted == 0 {
+		return v, nil
+	}
+	return kr.open(key, v)
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, sealSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,207 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve stores to other programs over HTTP or the Redis protocol.",
+	Long: `Serve stores to other programs over HTTP or the Redis protocol.
+
+With --http ADDR, pda serves a JSON API:
+
+  GET    /v1/dbs           list dbs
+  GET    /v1/{db}          list the entries of a db
+  GET    /v1/{db}/{key}    get a value
+  PUT    /v1/{db}/{key}    set a value from the request body
+  DELETE /v1/{db}/{key}    delete a key
+
+Pass ttl=DURATION or the X-Pda-Ttl header to PUT to expire the key, and
+secret=true or X-Pda-Secret: true to store it as a secret. Secret values
+are masked unless the server was started with --allow-secrets and the
+request also passes secret=true or X-Pda-Secret: true. A PUT or SET keeps
+the key's type and fails if the value does not fit it, and neither
+overwrites a list, hash or set.
+
+With --resp ADDR, pda speaks a subset of the Redis protocol (RESP2) so
+that redis-cli and Redis client libraries can use it: PING, ECHO, SELECT,
+GET, SET (with EX, PX, NX and XX), DEL, EXISTS, TTL, PTTL, EXPIRE, KEYS,
+SCAN and DBSIZE. SELECT takes a db name; SELECT 0 picks the default db.
+Keys are lowercased, as they are everywhere else in pda. GET on a secret
+fails unless the server was started with --allow-secrets.
+
+Stores are kept open between requests and closed after --idle-timeout, so
+other pda commands may wait for them. Start 'pda daemon' first to share
+stores between the server and other commands.
+
+Secret values and encrypted stores need a key. Pass --keyfile or set
+PDA_KEYFILE or PDA_PASSPHRASE; otherwise, if any store is encrypted, serve
+asks for the passphrase once when it starts. Requests never prompt.`,
+	Args: cobra.NoArgs,
+	RunE: serve,
+}
+
+func serve(cmd *cobra.Command, args []string) error {
+	httpAddr, err := cmd.Flags().GetString("http")
+	if err != nil {
+		return err
+	}
+	respAddr, err := cmd.Flags().GetString("resp")
+	if err != nil {
+		return err
+	}
+	allowSecrets, err := cmd.Flags().GetBool("allow-secrets")
+	if err != nil {
+		return err
+	}
+	idle, err := cmd.Flags().GetDuration("idle-timeout")
+	if err != nil {
+		return err
+	}
+	if httpAddr == "" && respAddr == "" {
+		return fmt.Errorf("nothing to serve; pass --http ADDR and/or --resp ADDR")
+	}
+
+	if err := loadServeKey(&Store{}); err != nil {
+		return err
+	}
+
+	if client := dialDaemon(); client != nil {
+		client.Close()
+	} else {
+		activePool = newStorePool(idle)
+		go activePool.reap()
+		defer activePool.closeAll()
+	}
+
+	errs := make(chan error, 2)
+	var shutdown []func()
+
+	if httpAddr != "" {
+		srv := &http.Server{
+			Addr:    httpAddr,
+			Handler: newHTTPAPI(allowSecrets),
+		}
+		ln, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			return err
+		}
+		shutdown = append(shutdown, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(ctx)
+		})
+		go func() {
+			if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+				return
+			}
+			errs <- nil
+		}()
+		fmt.Fprintf(cmd.ErrOrStderr(), "Serving HTTP on %s\n", ln.Addr())
+	}
+
+	if respAddr != "" {
+		srv := &respServer{store: &Store{}, allowSecrets: allowSecrets, keys: newKeyring()}
+		ln, err := net.Listen("tcp", respAddr)
+		if err != nil {
+			for _, stop := range shutdown {
+				stop()
+			}
+			return err
+		}
+		shutdown = append(shutdown, func() { ln.Close() })
+		go func() { errs <- srv.serve(ln) }()
+		fmt.Fprintf(cmd.ErrOrStderr(), "Serving RESP on %s\n", ln.Addr())
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-stop:
+		err = nil
+	case err = <-errs:
+	}
+	for _, stop := range shutdown {
+		stop()
+	}
+	return err
+}
+
+// loadServeKey resolves the key material once, before any request runs. It
+// prompts only when some store is encrypted and no keyfile or passphrase was
+// given, and then turns prompting off so requests fail with errNoSecretKey
+// instead of waiting on the server's terminal.
+func loadServeKey(store *Store) error {
+	defer disableKeyPrompt()
+	if keyfile != "" || os.Getenv(envKeyfile) != "" || os.Getenv(envPassphrase) != "" {
+		_, err := loadKeyMaterial()
+		return err
+	}
+	names, err := store.AllStores()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if isMemStore(name) {
+			continue
+		}
+		path, err := store.path(name)
+		if err != nil {
+			return err
+		}
+		meta, err := readStoreMeta(path)
+		if err != nil {
+			return err
+		}
+		if meta.Encrypted {
+			_, err := loadKeyMaterial()
+			if errors.Is(err, errNoSecretKey) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	serveCmd.Flags().String("http", "", "serve the HTTP API on this address (e.g. localhost:7070)")
+	serveCmd.Flags().String("resp", "", "serve the Redis protocol on this address (e.g. localhost:6379)")
+	serveCmd.Flags().Bool("allow-secrets", false, "let requests that ask for them read secret values")
+	serveCmd.Flags().Duration("idle-timeout", 2*time.Second, "close stores that have not been used for this long")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,409 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxHTTPValueSize = 64 << 20
+	secretMask       = "**********"
+)
+
+// httpAPI implements the JSON API behind `pda serve --http`.
+type httpAPI struct {
+	store        *Store
+	allowSecrets bool
+
+	mu   sync.Mutex
+	keys *keyring
+}
+
+func newHTTPAPI(allowSecrets bool) http.Handler {
+	api := &httpAPI{store: &Store{}, allowSecrets: allowSecrets, keys: newKeyring()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/dbs", api.listDBs)
+	mux.HandleFunc("GET /v1/{db}", api.list)
+	mux.HandleFunc("GET /v1/{db}/{key}", api.get)
+	mux.HandleFunc("PUT /v1/{db}/{key}", api.set)
+	mux.HandleFunc("DELETE /v1/{db}/{key}", api.del)
+	return mux
+}
+
+type httpDB struct {
+	Name string `json:"name"`
+	Root string `json:"root"`
+}
+
+func (api *httpAPI) listDBs(w http.ResponseWriter, r *http.Request) {
+	roots, err := api.store.roots()
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	seen := map[string]bool{}
+	dbs := []httpDB{}
+	for _, root := range roots {
+		names, err := storesIn(root.dir)
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				dbs = append(dbs, httpDB{Name: name, Root: root.kind})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, dbs)
+}
+
+func (api *httpAPI) list(w http.ResponseWriter, r *http.Request) {
+	db, err := api.existingDB(r.PathValue("db"))
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	mode := r.URL.Query().Get("encoding")
+	switch mode {
+	case "":
+		mode = "auto"
+	case "auto", "base64", "text":
+	default:
+		writeHTTPError(w, badRequest("unsupported encoding %q", mode))
+		return
+	}
+	values := true
+	if v := r.URL.Query().Get("values"); v != "" {
+		if values, err = strconv.ParseBool(v); err != nil {
+			writeHTTPError(w, badRequest("values must be true or false"))
+			return
+		}
+	}
+	reveal, err := api.revealSecrets(r)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	entries := []dumpEntry{}
+	err = api.store.Transaction(TransactionArgs{
+		key:      "@" + db,
+		readonly: true,
+		transact: func(tx Tx, k []byte) error {
+			return tx.Iterate(IterOptions{Values: values}, func(e Entry) error {
+				isSecret := e.Meta&metaSecret != 0
+				if !values || (isSecret && !reveal) {
+					entry, _ := newDumpEntry(e, nil, "auto")
+					entry.Encoding = ""
+					if values {
+						entry.Value = secretMask
+					}
+					entries = append(entries, entry)
+					return nil
+				}
+				v, err := api.revealValue(e)
+				if err != nil {
+					return err
+				}
+				entry, err := newDumpEntry(e, v, mode)
+				if err != nil {
+					return err
+				}
+				entries = append(entries, entry)
+				return nil
+			})
+		},
+	})
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (api *httpAPI) get(w http.ResponseWriter, r *http.Request) {
+	db, err := api.existingDB(r.PathValue("db"))
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	reveal, err := api.revealSecrets(r)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	ref, err := keyRef(r, db)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	var e Entry
+	err = api.store.Transaction(TransactionArgs{
+		key:      ref,
+		readonly: true,
+		transact: func(tx Tx, k []byte) error {
+			if e, err = tx.Get(k); err != nil {
+				return err
+			}
+			return checkKind(k, e, 0)
+		},
+	})
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	isSecret := e.Meta&metaSecret != 0
+	if isSecret && !reveal {
+		writeHTTPError(w, forbidden("%q is marked secret; pass secret=true to read it", e.Key))
+		return
+	}
+	v, err := api.revealValue(e)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Pda-Secret", strconv.FormatBool(isSecret))
+	if e.ExpiresAt > 0 {
+		w.Header().Set("X-Pda-Expires-At", strconv.FormatUint(e.ExpiresAt, 10))
+	}
+	w.Write(v)
+}
+
+func (api *httpAPI) set(w http.ResponseWriter, r *http.Request) {
+	db, err := api.store.parseDB(r.PathValue("db"), false)
+	if err != nil {
+		writeHTTPError(w, badRequest("%s", err))
+		return
+	}
+	ref, err := keyRef(r, db)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	value, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPValueSize))
+	if err != nil {
+		writeHTTPError(w, badRequest("%s", err))
+		return
+	}
+	secret, err := boolParam(r, "secret", "X-Pda-Secret")
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	var ttl time.Duration
+	if v := param(r, "ttl", "X-Pda-Ttl"); v != "" {
+		if ttl, err = time.ParseDuration(v); err != nil {
+			writeHTTPError(w, badRequest("ttl must be a duration such as 24h or 30m"))
+			return
+		}
+	}
+
+	plain := value
+	meta := byte(0x0)
+	if secret {
+		api.mu.Lock()
+		value, err = api.keys.seal(value)
+		api.mu.Unlock()
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+		meta = metaSecret | metaEncrypted
+	}
+
+	err = api.store.Transaction(TransactionArgs{
+		key: ref,
+		transact: func(tx Tx, k []byte) error {
+			cur, err := tx.Get(k)
+			created := errors.Is(err, errKeyNotFound)
+			if err == nil {
+				err = checkKind(k, cur, 0)
+			}
+			if err != nil && !created {
+				return err
+			}
+			typeMeta, err := typedMeta(tx, k, plain, typeNone, false)
+			if err != nil {
+				return badRequest("%s", err)
+			}
+			entry := Entry{Key: k, Value: value, Meta: meta | typeMeta}
+			if ttl != 0 {
+				entry.ExpiresAt = uint64(time.Now().Add(ttl).Unix())
+			}
+			if err := tx.Set(entry); err != nil {
+				return err
+			}
+			return annotateSet(tx, k, created, labelChange{})
+		},
+	})
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *httpAPI) del(w http.ResponseWriter, r *http.Request) {
+	db, err := api.existingDB(r.PathValue("db"))
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	ref, err := keyRef(r, db)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	err = api.store.Transaction(TransactionArgs{
+		key: ref,
+		transact: func(tx Tx, k []byte) error {
+			if _, err := tx.Get(k); err != nil {
+				return err
+			}
+			return tx.Delete(k)
+		},
+	})
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// existingDB resolves a db from the URL without creating it.
+func (api *httpAPI) existingDB(raw string) (string, error) {
+	db, err := api.store.parseDB(raw, false)
+	if err != nil {
+		return "", badRequest("%s", err)
+	}
+	if _, err := api.store.FindStore(db); err != nil {
+		var notFound errNotFound
+		if errors.As(err, &notFound) {
+			return "", fmt.Errorf("%q does not exist, %w", raw, err)
+		}
+		return "", err
+	}
+	return db, nil
+}
+
+// keyRef joins the key and db from a URL into the KEY@DB form Store expects.
+func keyRef(r *http.Request, db string) (string, error) {
+	key := r.PathValue("key")
+	if strings.Contains(key, "@") {
+		return "", badRequest("keys cannot contain @")
+	}
+	return key + "@" + db, nil
+}
+
+// revealSecrets reports whether the request asked for secret values, which
+// is only allowed when the server was started with --allow-secrets.
+func (api *httpAPI) revealSecrets(r *http.Request) (bool, error) {
+	reveal, err := boolParam(r, "secret", "X-Pda-Secret")
+	if err != nil {
+		return false, err
+	}
+	if reveal && !api.allowSecrets {
+		return false, forbidden("this server was not started with --allow-secrets")
+	}
+	return reveal, nil
+}
+
+func (api *httpAPI) revealValue(e Entry) ([]byte, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.keys.reveal(string(e.Key), e.Meta, e.Value)
+}
+
+// errHTTP is an error that maps to a specific response status.
+type errHTTP struct {
+	status int
+	msg    string
+}
+
+func (err errHTTP) Error() string {
+	return err.msg
+}
+
+func badRequest(format string, args ...any) error {
+	return errHTTP{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func forbidden(format string, args ...any) error {
+	return errHTTP{http.StatusForbidden, fmt.Sprintf(format, args...)}
+}
+
+func param(r *http.Request, query, header string) string {
+	if v := r.URL.Query().Get(query); v != "" {
+		return v
+	}
+	return r.Header.Get(header)
+}
+
+func boolParam(r *http.Request, query, header string) (bool, error) {
+	v := param(r, query, header)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, badRequest("%s must be true or false", strings.ToLower(query))
+	}
+	return b, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeHTTPError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var httpErr errHTTP
+	var notFound errNotFound
+	var lockTimeout errLockTimeout
+	switch {
+	case errors.As(err, &httpErr):
+		status = httpErr.status
+	case errors.Is(err, errKeyNotFound), errors.As(err, &notFound):
+		status = http.StatusNotFound
+	case errors.As(err, &lockTimeout):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, errReadOnly):
+		status = http.StatusForbidden
+	case errors.Is(err, errWrongKind):
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
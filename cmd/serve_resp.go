@@ -0,0 +1,635 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxRESPBulkSize = 512 << 20
+
+// respServer implements the subset of the Redis protocol (RESP2) behind
+// `pda serve --resp`. Each connection starts on the default db and can
+// switch with SELECT.
+type respServer struct {
+	store        *Store
+	allowSecrets bool
+
+	mu   sync.Mutex
+	keys *keyring
+}
+
+func (srv *respServer) serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go srv.handle(conn)
+	}
+}
+
+type respConn struct {
+	srv *respServer
+	db  string
+	r   *bufio.Reader
+	w   *bufio.Writer
+}
+
+func (srv *respServer) handle(conn net.Conn) {
+	defer conn.Close()
+	c := &respConn{srv: srv, db: defaultDB(), r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	for {
+		args, err := c.readCommand()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				c.writeError("ERR protocol error: " + err.Error())
+				c.w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quit := strings.EqualFold(args[0], "quit")
+		c.dispatch(args)
+		if err := c.w.Flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+// readCommand reads either a RESP array of bulk strings or an inline
+// command, as sent by telnet-style clients.
+func (c *respConn) readCommand() ([]string, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid multibulk length")
+	}
+	args := make([]string, 0, n)
+	for range n {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected '$', got %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > maxRESPBulkSize {
+			return nil, fmt.Errorf("invalid bulk length")
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func (c *respConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *respConn) writeSimple(s string) { fmt.Fprintf(c.w, "+%s\r\n", s) }
+func (c *respConn) writeError(s string)  { fmt.Fprintf(c.w, "-%s\r\n", s) }
+func (c *respConn) writeInt(n int64)     { fmt.Fprintf(c.w, ":%d\r\n", n) }
+func (c *respConn) writeNull()           { c.w.WriteString("$-1\r\n") }
+
+func (c *respConn) writeBulk(b []byte) {
+	fmt.Fprintf(c.w, "$%d\r\n", len(b))
+	c.w.Write(b)
+	c.w.WriteString("\r\n")
+}
+
+func (c *respConn) writeArray(items []string) {
+	fmt.Fprintf(c.w, "*%d\r\n", len(items))
+	for _, item := range items {
+		c.writeBulk([]byte(item))
+	}
+}
+
+func (c *respConn) dispatch(args []string) {
+	name := strings.ToUpper(args[0])
+	handler, ok := respCommands[name]
+	if !ok {
+		c.writeError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		return
+	}
+	if (handler.arity > 0 && len(args) != handler.arity) || len(args) < -handler.arity {
+		c.writeError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+		return
+	}
+	if err := handler.fn(c, args[1:]); err != nil {
+		c.writeRESPError(err)
+	}
+}
+
+func (c *respConn) writeRESPError(err error) {
+	var lockTimeout errLockTimeout
+	switch {
+	case errors.As(err, &lockTimeout):
+		c.writeError("BUSY " + err.Error())
+	case strings.HasPrefix(err.Error(), "ERR ") || strings.HasPrefix(err.Error(), "WRONGTYPE "):
+		c.writeError(err.Error())
+	default:
+		c.writeError("ERR " + err.Error())
+	}
+}
+
+// respCommand is a RESP command handler. A positive arity is an exact
+// argument count including the command name; a negative one is a minimum.
+type respCommand struct {
+	arity int
+	fn    func(c *respConn, args []string) error
+}
+
+var respCommands map[string]respCommand
+
+func init() {
+	respCommands = map[string]respCommand{
+		"PING":    {-1, respPing},
+		"ECHO":    {2, respEcho},
+		"QUIT":    {1, respOK},
+		"CLIENT":  {-2, respOK},
+		"COMMAND": {-1, respCommandInfo},
+		"SELECT":  {2, respSelect},
+		"GET":     {2, respGet},
+		"SET":     {-3, respSet},
+		"DEL":     {-2, respDel},
+		"EXISTS":  {-2, respExists},
+		"TTL":     {2, respTTL(time.Second)},
+		"PTTL":    {2, respTTL(time.Millisecond)},
+		"EXPIRE":  {3, respExpire},
+		"KEYS":    {2, respKeys},
+		"SCAN":    {-2, respScan},
+		"DBSIZE":  {1, respDBSize},
+	}
+}
+
+func (c *respConn) transaction(key string, readonly bool, fn func(tx Tx, k []byte) error) error {
+	if strings.Contains(key, "@") {
+		return fmt.Errorf("keys cannot contain @")
+	}
+	return c.srv.store.Transaction(TransactionArgs{
+		key:      key + "@" + c.db,
+		readonly: readonly,
+		transact: fn,
+	})
+}
+
+func respPing(c *respConn, args []string) error {
+	if len(args) > 0 {
+		c.writeBulk([]byte(args[0]))
+		return nil
+	}
+	c.writeSimple("PONG")
+	return nil
+}
+
+func respEcho(c *respConn, args []string) error {
+	c.writeBulk([]byte(args[0]))
+	return nil
+}
+
+func respOK(c *respConn, args []string) error {
+	c.writeSimple("OK")
+	return nil
+}
+
+// respCommandInfo answers COMMAND and COMMAND DOCS, which redis-cli sends
+// on connect, with an empty list.
+func respCommandInfo(c *respConn, args []string) error {
+	c.writeArray(nil)
+	return nil
+}
+
+// respSelect switches db. Numeric index 0 is the default db so that clients
+// which always SELECT 0 keep working; anything else is a store name.
+func respSelect(c *respConn, args []string) error {
+	if args[0] == "0" {
+		c.db = defaultDB()
+		c.writeSimple("OK")
+		return nil
+	}
+	db, err := c.srv.store.parseDB(args[0], false)
+	if err != nil {
+		return err
+	}
+	c.db = db
+	c.writeSimple("OK")
+	return nil
+}
+
+func respGet(c *respConn, args []string) error {
+	var e Entry
+	err := c.transaction(args[0], true, func(tx Tx, k []byte) error {
+		var err error
+		e, err = tx.Get(k)
+		return err
+	})
+	if errors.Is(err, errKeyNotFound) {
+		c.writeNull()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if e.Meta&metaKind != 0 {
+		return fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	if e.Meta&metaSecret != 0 {
+		if !c.srv.allowSecrets {
+			return fmt.Errorf("%q is marked secret and this server was not started with --allow-secrets", args[0])
+		}
+		c.srv.mu.Lock()
+		v, err := c.srv.keys.reveal(args[0], e.Meta, e.Value)
+		c.srv.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		e.Value = v
+	}
+	c.writeBulk(e.Value)
+	return nil
+}
+
+func respSet(c *respConn, args []string) error {
+	var ttl time.Duration
+	var nx, xx bool
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return fmt.Errorf("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("ERR invalid expire time in 'set' command")
+			}
+			ttl = time.Duration(n) * time.Second
+			if opt == "PX" {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+			i++
+		default:
+			return fmt.Errorf("ERR syntax error")
+		}
+	}
+	if nx && xx {
+		return fmt.Errorf("ERR syntax error")
+	}
+
+	written := false
+	err := c.transaction(args[0], false, func(tx Tx, k []byte) error {
+		cur, err := tx.Get(k)
+		exists := err == nil
+		if err != nil && !errors.Is(err, errKeyNotFound) {
+			return err
+		}
+		if (nx && exists) || (xx && !exists) {
+			return nil
+		}
+		if exists && cur.Meta&metaKind != 0 {
+			return fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")
+		}
+		typeMeta, err := typedMeta(tx, k, []byte(args[1]), typeNone, false)
+		if err != nil {
+			return err
+		}
+		entry := Entry{Key: k, Value: []byte(args[1]), Meta: typeMeta}
+		if ttl > 0 {
+			entry.ExpiresAt = uint64(time.Now().Add(ttl).Unix())
+		}
+		written = true
+		if err := tx.Set(entry); err != nil {
+			return err
+		}
+		return annotateSet(tx, k, !exists, labelChange{})
+	})
+	if err != nil {
+		return err
+	}
+	if !written {
+		c.writeNull()
+		return nil
+	}
+	c.writeSimple("OK")
+	return nil
+}
+
+func respDel(c *respConn, args []string) error {
+	var n int64
+	for _, key := range args {
+		err := c.transaction(key, false, func(tx Tx, k []byte) error {
+			if _, err := tx.Get(k); err != nil {
+				return err
+			}
+			return tx.Delete(k)
+		})
+		if errors.Is(err, errKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		n++
+	}
+	c.writeInt(n)
+	return nil
+}
+
+func respExists(c *respConn, args []string) error {
+	var n int64
+	for _, key := range args {
+		err := c.transaction(key, true, func(tx Tx, k []byte) error {
+			_, err := tx.Get(k)
+			return err
+		})
+		if errors.Is(err, errKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		n++
+	}
+	c.writeInt(n)
+	return nil
+}
+
+// respTTL answers TTL and PTTL, reporting the remaining time in unit.
+func respTTL(unit time.Duration) func(c *respConn, args []string) error {
+	return func(c *respConn, args []string) error {
+		return respRemaining(c, args[0], unit)
+	}
+}
+
+func respRemaining(c *respConn, key string, unit time.Duration) error {
+	var e Entry
+	err := c.transaction(key, true, func(tx Tx, k []byte) error {
+		var err error
+		e, err = tx.Get(k)
+		return err
+	})
+	if errors.Is(err, errKeyNotFound) {
+		c.writeInt(-2)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if e.ExpiresAt == 0 {
+		c.writeInt(-1)
+		return nil
+	}
+	remaining := max(time.Until(time.Unix(int64(e.ExpiresAt), 0)), 0)
+	c.writeInt(int64(remaining.Round(unit) / unit))
+	return nil
+}
+
+func respExpire(c *respConn, args []string) error {
+	seconds, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("ERR value is not an integer or out of range")
+	}
+	err = c.transaction(args[0], false, func(tx Tx, k []byte) error {
+		e, err := tx.Get(k)
+		if err != nil {
+			return err
+		}
+		if seconds <= 0 {
+			return tx.Delete(k)
+		}
+		e.ExpiresAt = uint64(time.Now().Add(time.Duration(seconds) * time.Second).Unix())
+		return tx.Set(e)
+	})
+	if errors.Is(err, errKeyNotFound) {
+		c.writeInt(0)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.writeInt(1)
+	return nil
+}
+
+// matchingKeys returns the keys in the current db that match a Redis glob.
+func (c *respConn) matchingKeys(pattern string) ([]string, error) {
+	var keys []string
+	err := c.srv.store.Transaction(TransactionArgs{
+		key:      "@" + c.db,
+		readonly: true,
+		transact: func(tx Tx, k []byte) error {
+			return tx.Iterate(IterOptions{}, func(e Entry) error {
+				if globMatch(pattern, string(e.Key)) {
+					keys = append(keys, string(e.Key))
+				}
+				return nil
+			})
+		},
+	})
+	return keys, err
+}
+
+func respKeys(c *respConn, args []string) error {
+	keys, err := c.matchingKeys(args[0])
+	if err != nil {
+		return err
+	}
+	c.writeArray(keys)
+	return nil
+}
+
+// respScan pages through the sorted key list. The cursor is an offset into
+// that list, so keys added between calls may be skipped or repeated, which
+// SCAN allows.
+func respScan(c *respConn, args []string) error {
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil || cursor < 0 {
+		return fmt.Errorf("ERR invalid cursor")
+	}
+	pattern, count := "*", 10
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return fmt.Errorf("ERR syntax error")
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			if count, err = strconv.Atoi(args[i+1]); err != nil || count < 1 {
+				return fmt.Errorf("ERR value is not an integer or out of range")
+			}
+		default:
+			return fmt.Errorf("ERR syntax error")
+		}
+	}
+	keys, err := c.matchingKeys(pattern)
+	if err != nil {
+		return err
+	}
+	start := min(cursor, len(keys))
+	end := min(start+count, len(keys))
+	next := "0"
+	if end < len(keys) {
+		next = strconv.Itoa(end)
+	}
+	fmt.Fprintf(c.w, "*2\r\n")
+	c.writeBulk([]byte(next))
+	c.writeArray(keys[start:end])
+	return nil
+}
+
+func respDBSize(c *respConn, args []string) error {
+	var n int64
+	err := c.srv.store.Transaction(TransactionArgs{
+		key:      "@" + c.db,
+		readonly: true,
+		transact: func(tx Tx, k []byte) error {
+			return tx.Iterate(IterOptions{}, func(e Entry) error {
+				n++
+				return nil
+			})
+		},
+	})
+	if err != nil {
+		return err
+	}
+	c.writeInt(n)
+	return nil
+}
+
+// globMatch reports whether s matches a Redis glob pattern. Unlike
+// path.Match, '*' matches any byte sequence including '/', and malformed
+// patterns never error; they follow Redis's stringmatchlen rules.
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			pattern = pattern[1:]
+			not := len(pattern) > 0 && pattern[0] == '^'
+			if not {
+				pattern = pattern[1:]
+			}
+			match := false
+			for {
+				if len(pattern) == 0 {
+					// Unterminated class: Redis treats the end of the
+					// pattern as the closing bracket.
+					break
+				}
+				if pattern[0] == '\\' && len(pattern) >= 2 {
+					pattern = pattern[1:]
+					if pattern[0] == s[0] {
+						match = true
+					}
+				} else if pattern[0] == ']' {
+					break
+				} else if len(pattern) >= 3 && pattern[1] == '-' {
+					lo, hi := pattern[0], pattern[2]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					if s[0] >= lo && s[0] <= hi {
+						match = true
+					}
+					pattern = pattern[2:]
+				} else if pattern[0] == s[0] {
+					match = true
+				}
+				pattern = pattern[1:]
+			}
+			if not {
+				match = !match
+			}
+			if !match {
+				return false
+			}
+			s = s[1:]
+			if len(pattern) == 0 {
+				return len(s) == 0
+			}
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(s) == 0
+}
@@ -22,9 +22,11 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"time"
 
-	"github.com/dgraph-io/badger/v4"
 	"github.com/spf13/cobra"
 )
 
@@ -32,8 +34,30 @@ import (
 var setCmd = &cobra.Command{
 	Use:   "set KEY[@DB] [VALUE]",
 	Short: "Set a value for a key by passing VALUE or from Stdin. Optionally specify a db.",
-	Args:  cobra.RangeArgs(1, 2),
-	RunE:  set,
+	Long: `Set a value for a key by passing VALUE or from Stdin. Optionally specify a db.
+
+The --if flags make the set conditional: it only happens if the key is
+absent, exists, holds OLD, or is at version N (see list --show-version).
+The check and the write are one transaction, so concurrent scripts can use
+them to coordinate. If the condition does not hold, nothing is written and
+pda exits with status 3.
+
+--path sets one field of a value that holds a JSON document, e.g.
+--path .db.port, creating the key and any objects on the way if need be.
+VALUE is used as JSON if it parses as JSON and as a string otherwise. The
+rest of the document, its secrecy and its TTL are kept unless --secret or
+--ttl are passed. If the value is not valid JSON, it is left as it is.
+
+--type checks the value is a string, int, float, bool, json, duration,
+timestamp (RFC 3339) or bytes, and records the type for later sets to be
+checked against, and for list to sort and filter by. A key keeps its type
+until set with another.
+
+--label NAME=VALUE adds a label to the key, and --note replaces its note;
+see pda label.`,
+	Args:        cobra.RangeArgs(1, 2),
+	Annotations: writesData,
+	RunE:        set,
 }
 
 func set(cmd *cobra.Command, args []string) error {
@@ -58,28 +82,220 @@ func set(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if !cmd.Flags().Changed("ttl") {
+		v, err := configValue("set.ttl")
+		if err != nil {
+			return err
+		}
+		if ttl, err = time.ParseDuration(v); err != nil {
+			return fmt.Errorf("set.ttl: %w", err)
+		}
+	}
+
+	var cond setCondition
+	if cond.absent, err = cmd.Flags().GetBool("if-absent"); err != nil {
+		return err
+	}
+	if cond.exists, err = cmd.Flags().GetBool("if-exists"); err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("if-value") {
+		v, err := cmd.Flags().GetString("if-value")
+		if err != nil {
+			return err
+		}
+		cond.value = &v
+	}
+	if cmd.Flags().Changed("if-version") {
+		n, err := cmd.Flags().GetUint64("if-version")
+		if err != nil {
+			return err
+		}
+		cond.version = &n
+	}
+	if err := cond.validate(); err != nil {
+		return err
+	}
+	var vt valueType
+	typed := cmd.Flags().Changed("type")
+	if typed {
+		name, err := cmd.Flags().GetString("type")
+		if err != nil {
+			return err
+		}
+		if vt, err = parseValueType(name); err != nil {
+			return err
+		}
+	}
+	var steps []jsonStep
+	patch := cmd.Flags().Changed("path")
+	if patch && typed {
+		return fmt.Errorf("--path keeps the key's type; it cannot be combined with --type")
+	}
+	if patch {
+		path, err := cmd.Flags().GetString("path")
+		if err != nil {
+			return err
+		}
+		if steps, err = parseJSONPath(path); err != nil {
+			return err
+		}
+	}
+
+	labels, err := labelFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	keys := newKeyring()
+	plain := value
+	meta := byte(0x0)
+	if secret && !patch {
+		sealed, err := keys.seal(value)
+		if err != nil {
+			return err
+		}
+		value = sealed
+		meta = metaSecret | metaEncrypted
+	}
 
 	trans := TransactionArgs{
 		key:      args[0],
 		readonly: false,
 		sync:     false,
-		transact: func(tx *badger.Txn, k []byte) error {
-			entry := badger.NewEntry(k, value)
-			if secret {
-				entry = entry.WithMeta(metaSecret)
+		transact: func(tx Tx, k []byte) error {
+			if err := cond.check(tx, k, keys); err != nil {
+				return err
 			}
-			if ttl != 0 {
-				entry = entry.WithTTL(ttl)
+			_, err := tx.Get(k)
+			created := errors.Is(err, errKeyNotFound)
+			if err != nil && !created {
+				return err
 			}
-			return tx.SetEntry(entry)
+			if patch {
+				err = setField(tx, k, keys, steps, value, secret, ttl, cmd.Flags().Changed("ttl"))
+			} else {
+				err = setValue(tx, k, value, plain, meta, vt, typed, ttl)
+			}
+			if err != nil {
+				return err
+			}
+			return annotateSet(tx, k, created, labels)
 		},
 	}
 
-	return store.Transaction(trans)
+	err = store.Transaction(trans)
+	if errors.As(err, new(preconditionError)) {
+		cmd.SilenceUsage = true
+	}
+	return err
+}
+
+// setValue sets k to value, whose plaintext is plain, with the given type.
+func setValue(tx Tx, k, value, plain []byte, meta byte, vt valueType, typed bool, ttl time.Duration) error {
+	typeMeta, err := typedMeta(tx, k, plain, vt, typed)
+	if err != nil {
+		return err
+	}
+	entry := Entry{Key: k, Value: value, Meta: meta | typeMeta}
+	if ttl != 0 {
+		entry.ExpiresAt = uint64(time.Now().Add(ttl).Unix())
+	}
+	return tx.Set(entry)
+}
+
+// setField sets the field at steps of the JSON document at k to value.
+func setField(tx Tx, k []byte, keys *keyring, steps []jsonStep, value []byte, secret bool, ttl time.Duration, ttlChanged bool) error {
+	_, err := tx.Get(k)
+	created := errors.Is(err, errKeyNotFound)
+	e, err := patchJSON(tx, k, keys, func(doc any) (any, error) {
+		return setJSON(doc, steps, jsonArgValue(value), "")
+	})
+	if err != nil {
+		return err
+	}
+	if secret && e.Meta&metaEncrypted == 0 {
+		if e.Value, err = keys.seal(e.Value); err != nil {
+			return err
+		}
+		e.Meta |= metaSecret | metaEncrypted
+	}
+	if created || ttlChanged {
+		e.ExpiresAt = 0
+		if ttl != 0 {
+			e.ExpiresAt = uint64(time.Now().Add(ttl).Unix())
+		}
+	}
+	return tx.Set(e)
+}
+
+// preconditionError is returned by a conditional write whose condition does
+// not hold. Execute exits with its ExitCode, exitPrecondition, while pda
+// shell just prints it and carries on.
+type preconditionError struct {
+	reason string
+}
+
+func (err preconditionError) Error() string {
+	return "precondition failed: " + err.reason
+}
+
+func (preconditionError) ExitCode() int {
+	return exitPrecondition
+}
+
+// setCondition is what a conditional set needs to find before it writes.
+type setCondition struct {
+	absent  bool
+	exists  bool
+	value   *string
+	version *uint64
+}
+
+func (c setCondition) validate() error {
+	if c.absent && (c.exists || c.value != nil || c.version != nil) {
+		return fmt.Errorf("--if-absent cannot be combined with the other --if flags")
+	}
+	return nil
+}
+
+// check returns a preconditionError giving the reason unless tx holds
+// what c asks for under key.
+func (c setCondition) check(tx Tx, key []byte, keys *keyring) error {
+	cur, err := tx.Get(key)
+	found := err == nil
+	if err != nil && !errors.Is(err, errKeyNotFound) {
+		return err
+	}
+	switch {
+	case c.absent && found:
+		return preconditionError{fmt.Sprintf("%q exists", key)}
+	case (c.exists || c.value != nil || c.version != nil) && !found:
+		return preconditionError{fmt.Sprintf("%q does not exist", key)}
+	case c.version != nil && cur.Version != *c.version:
+		return preconditionError{fmt.Sprintf("%q is at version %d, not %d", key, cur.Version, *c.version)}
+	case c.value != nil:
+		v, err := keys.reveal(string(key), cur.Meta, cur.Value)
+		if err != nil {
+			return err
+		}
+		if string(v) != *c.value {
+			return preconditionError{fmt.Sprintf("%q does not hold %q", key, *c.value)}
+		}
+	}
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(setCmd)
-	setCmd.Flags().Bool("secret", false, "Mark the stored value as a secret")
+	setCmd.Flags().Bool("secret", false, "Mark the stored value as a secret and encrypt it")
 	setCmd.Flags().DurationP("ttl", "t", 0, "Expire the key after the provided duration (e.g. 24h, 30m)")
+	setCmd.Flags().Bool("if-absent", false, "Only set the key if it does not exist")
+	setCmd.Flags().Bool("if-exists", false, "Only set the key if it exists")
+	setCmd.Flags().String("if-value", "", "Only set the key if it holds this value")
+	setCmd.Flags().Uint64("if-version", 0, "Only set the key if it is at this version")
+	setCmd.Flags().String("path", "", "Set only this field of a JSON value, e.g. .db.port")
+	setCmd.Flags().StringArray("label", nil, "Add a label to the key, as NAME=VALUE (repeatable)")
+	setCmd.Flags().String("note", "", "Replace the key's note")
+	setCmd.Flags().String("type", "", "Check the value is of this type and record it: string, int, float, bool, json, duration, timestamp or bytes")
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestConditionalSetExitCodes(t *testing.T) {
+	db, err := openBadgerMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, _ := db.NewTx(true)
+	if err := tx.Set(Entry{Key: []byte("k"), Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx, _ = db.NewTx(false)
+	defer tx.Discard()
+	cur, err := tx.Get([]byte("k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := func(v string) *string { return &v }
+	version := func(v uint64) *uint64 { return &v }
+	tests := []struct {
+		name string
+		cond setCondition
+		key  string
+		code int
+	}{
+		{"absent on missing key", setCondition{absent: true}, "nope", 0},
+		{"absent on existing key", setCondition{absent: true}, "k", exitPrecondition},
+		{"exists on existing key", setCondition{exists: true}, "k", 0},
+		{"exists on missing key", setCondition{exists: true}, "nope", exitPrecondition},
+		{"value matches", setCondition{value: value("v")}, "k", 0},
+		{"value differs", setCondition{value: value("w")}, "k", exitPrecondition},
+		{"value on missing key", setCondition{value: value("v")}, "nope", exitPrecondition},
+		{"version matches", setCondition{version: version(cur.Version)}, "k", 0},
+		{"version differs", setCondition{version: version(cur.Version + 1)}, "k", exitPrecondition},
+	}
+	for _, tt := range tests {
+		err := tt.cond.check(tx, []byte(tt.key), newKeyring())
+		if got := exitCode(err); got != tt.code {
+			t.Errorf("%s: exit code %d (%v), want %d", tt.name, got, err, tt.code)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, 0},
+		{errors.New("boom"), 1},
+		{preconditionError{"\"k\" exists"}, exitPrecondition},
+		{fmt.Errorf("retrying: %w", preconditionError{"\"k\" exists"}), exitPrecondition},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.code {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.code)
+		}
+	}
+}
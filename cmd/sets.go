@@ -0,0 +1,163 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// saddCmd represents the sadd command
+var saddCmd = &cobra.Command{
+	Use:   "sadd KEY[@DB] MEMBER...",
+	Short: "Add members to a set. Optionally specify a db.",
+	Long: `Add members to a set, creating it if need be. A set holds each member at
+most once; adding one it already holds does nothing.`,
+	Args:        cobra.MinimumNArgs(2),
+	Annotations: writesData,
+	RunE:        sadd,
+}
+
+// sremCmd represents the srem command
+var sremCmd = &cobra.Command{
+	Use:         "srem KEY[@DB] MEMBER...",
+	Short:       "Remove members from a set. Optionally specify a db.",
+	Args:        cobra.MinimumNArgs(2),
+	Annotations: writesData,
+	RunE:        srem,
+}
+
+// smembersCmd represents the smembers command
+var smembersCmd = &cobra.Command{
+	Use:   "smembers KEY[@DB]",
+	Short: "Print the members of a set, one per line.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  smembers,
+}
+
+// sismemberCmd represents the sismember command
+var sismemberCmd = &cobra.Command{
+	Use:   "sismember KEY[@DB] MEMBER",
+	Short: "Print whether a set holds a member: true or false.",
+	Args:  cobra.ExactArgs(2),
+	RunE:  sismember,
+}
+
+func sadd(cmd *cobra.Command, args []string) error {
+	return changeSet(cmd, args, putElem)
+}
+
+func srem(cmd *cobra.Command, args []string) error {
+	return changeSet(cmd, args, func(tx Tx, k []byte, c *collectionHead, member, _ []byte) error {
+		return deleteElem(tx, k, c, member)
+	})
+}
+
+// changeSet applies change to each member of the set args[0] given in the
+// rest of args.
+func changeSet(cmd *cobra.Command, args []string, change func(tx Tx, k []byte, c *collectionHead, member, value []byte) error) error {
+	store := &Store{}
+	var size uint64
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: false,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			e, c, found, err := readCollection(tx, k, kindSet)
+			if err != nil {
+				return err
+			}
+			for _, member := range args[1:] {
+				if err := change(tx, k, &c, []byte(member), nil); err != nil {
+					return err
+				}
+			}
+			size = c.tail - c.head
+			if !found && size == 0 {
+				return nil
+			}
+			if err := writeCollection(tx, e, c); err != nil {
+				return err
+			}
+			return annotateSet(tx, k, !found, labelChange{})
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.ErrOrStderr(), holds(args[0], size, "member"))
+	return nil
+}
+
+func smembers(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	out := cmd.OutOrStdout()
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: true,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			_, c, found, err := readCollection(tx, k, kindSet)
+			if err != nil || !found {
+				return err
+			}
+			return eachElem(tx, k, c, func(member, _ []byte) error {
+				store.PrintTo(out, "%s\n", false, member)
+				return nil
+			})
+		},
+	}
+	return store.Transaction(trans)
+}
+
+func sismember(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	var member bool
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: true,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			_, c, found, err := readCollection(tx, k, kindSet)
+			if err != nil || !found {
+				return err
+			}
+			_, err = tx.Get(elemKey(k, c.id, []byte(args[1])))
+			if errors.Is(err, errKeyNotFound) {
+				return nil
+			}
+			member = err == nil
+			return err
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), member)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(saddCmd, sremCmd, smembersCmd, sismemberCmd)
+}
@@ -22,17 +22,18 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/agnivade/levenshtein"
-	"github.com/dgraph-io/badger/v4"
-	gap "github.com/muesli/go-app-paths"
+	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
 
@@ -40,10 +41,27 @@ type errNotFound struct {
 	suggestions []string
 }
 
+const maxTxnAttempts = 10
+
+var (
+	lockTimeoutFlag time.Duration
+	readOnlyFlag    bool
+)
+
 const (
-	metaSecret byte = 0x1
+	metaSecret    byte = 0x1
+	metaEncrypted byte = 0x2
 )
 
+type errLockTimeout struct {
+	db      string
+	timeout time.Duration
+}
+
+func (err errLockTimeout) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for another pda process to release @%s; raise --lock-timeout to wait longer", err.timeout, err.db)
+}
+
 func (err errNotFound) Error() string {
 	if len(err.suggestions) == 0 {
 		return "no suggestions found"
@@ -53,11 +71,14 @@ func (err errNotFound) Error() string {
 
 type Store struct{}
 
+// TransactionArgs describes a Store.Transaction. Writes are journaled for
+// pda undo unless noJournal is set.
 type TransactionArgs struct {
-	key      string
-	readonly bool
-	sync     bool
-	transact func(tx *badger.Txn, key []byte) error
+	key       string
+	readonly  bool
+	sync      bool
+	noJournal bool
+	transact  func(tx Tx, key []byte) error
 }
 
 func (s *Store) Transaction(args TransactionArgs) error {
@@ -66,7 +87,11 @@ func (s *Store) Transaction(args TransactionArgs) error {
 		return err
 	}
 
-	db, err := s.open(dbName)
+	open := s.open
+	if args.readonly {
+		open = s.openReadOnly
+	}
+	db, err := open(dbName)
 	if err != nil {
 		return err
 	}
@@ -79,8 +104,32 @@ func (s *Store) Transaction(args TransactionArgs) error {
 		}
 	}
 
-	tx := db.NewTransaction(!args.readonly)
+	return s.retry(db, args, k)
+}
+
+// retry runs a transaction against an open store, starting it over when it
+// conflicts with another.
+func (s *Store) retry(db Backend, args TransactionArgs, k []byte) error {
+	for attempt := 1; ; attempt++ {
+		err := s.transact(db, args, k)
+		if errors.Is(err, errConflict) && attempt < maxTxnAttempts {
+			continue
+		}
+		return err
+	}
+}
+
+func (s *Store) transact(db Backend, args TransactionArgs, k []byte) error {
+	tx, err := db.NewTx(!args.readonly)
+	if err != nil {
+		return err
+	}
 	defer tx.Discard()
+	if !args.readonly && !args.noJournal {
+		if tx, err = newJournalTx(tx); err != nil {
+			return err
+		}
+	}
 
 	if err := args.transact(tx, k); err != nil {
 		return err
@@ -122,21 +171,29 @@ func (s *Store) formatBytes(includeBinary bool, v []byte) string {
 }
 
 func (s *Store) AllStores() ([]string, error) {
-	path, err := s.path()
-	if err != nil {
-		return nil, err
-	}
-	dirs, err := os.ReadDir(path)
+	roots, err := s.roots()
 	if err != nil {
 		return nil, err
 	}
+	seen := map[string]bool{}
 	var stores []string
-	for _, e := range dirs {
-		if e.IsDir() {
-			stores = append(stores, e.Name())
+	for _, root := range roots {
+		names, err := storesIn(root.dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				stores = append(stores, name)
+			}
 		}
 	}
-	return stores, nil
+	mem, err := memStores()
+	if err != nil {
+		return nil, err
+	}
+	return append(stores, mem...), nil
 }
 
 func (s *Store) FindStore(k string) (string, error) {
@@ -144,6 +201,9 @@ func (s *Store) FindStore(k string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if isMemStore(n) {
+		return n, nil
+	}
 	path, err := s.path(n)
 	if err != nil {
 		return "", err
@@ -169,7 +229,7 @@ func (s *Store) parse(k string, defaults bool) ([]byte, string, error) {
 	case 1:
 		key = strings.ToLower(ps[0])
 		if defaults {
-			db = "default"
+			db = defaultDB()
 		}
 	case 2:
 		key = strings.ToLower(ps[0])
@@ -187,34 +247,120 @@ func (s *Store) parseDB(v string, defaults bool) (string, error) {
 	}
 	if db == "" {
 		if defaults {
-			return "default", nil
+			return defaultDB(), nil
 		}
 		return "", fmt.Errorf("bad db format, use DB or @DB")
 	}
 	return strings.ToLower(db), nil
 }
 
-func (s *Store) open(name string) (*badger.DB, error) {
+func (s *Store) open(name string) (Backend, error) {
+	return s.openMode(name, false)
+}
+
+// openReadOnly opens a store for reading only. Badger and bolt both let any
+// number of read-only processes share a store, but a writer waits for them
+// to close it unless the daemon holds the store.
+func (s *Store) openReadOnly(name string) (Backend, error) {
+	return s.openMode(name, true)
+}
+
+// openMode opens a store. Under --read-only every store is opened read-only;
+// otherwise a read-only open falls back to read-write for stores that do not
+// exist yet or need recovering.
+func (s *Store) openMode(name string, readOnly bool) (Backend, error) {
 	if name == "" {
-		name = "default"
+		name = defaultDB()
 	}
 	path, err := s.path(name)
 	if err != nil {
 		return nil, err
 	}
-	return badger.Open(badger.DefaultOptions(path).WithLoggingLevel(badger.ERROR))
+	readOnly = readOnly || readOnlyFlag
+	meta := storeMeta{Backend: backendMemory}
+	if !isMemStore(name) {
+		if _, err := os.Stat(path); readOnly && os.IsNotExist(err) {
+			if readOnlyFlag {
+				return nil, fmt.Errorf("@%s does not exist", name)
+			}
+			readOnly = false
+		}
+		if meta, err = readStoreMeta(path); err != nil {
+			return nil, err
+		}
+	}
+	var key []byte
+	if meta.Encrypted {
+		material, err := loadKeyMaterial()
+		if err != nil {
+			return nil, err
+		}
+		key = deriveKey(material, meta.Salt)
+	}
+	timeout, err := s.lockTimeout()
+	if err != nil {
+		return nil, err
+	}
+
+	var db Backend
+	if activePool != nil {
+		db, err = activePool.open(name, path, meta, key, timeout)
+	} else if client := dialDaemon(); client != nil {
+		db, err = openRemote(client, name, path, meta, key, timeout)
+	} else if isMemStore(name) {
+		return nil, errNoMemoryHost
+	} else {
+		db, err = waitOpen(name, path, meta, key, readOnly, timeout)
+		if errors.Is(err, errNeedsRecovery) && !readOnlyFlag {
+			db, err = waitOpen(name, path, meta, key, false, timeout)
+		}
+	}
+	if errors.Is(err, errWrongKey) || errors.Is(err, errNeedsRecovery) {
+		return nil, fmt.Errorf("cannot open @%s; %w", name, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if readOnlyFlag {
+		db = readOnlyBackend{db}
+	}
+	return db, nil
+}
+
+// lockTimeout is how long open waits for another pda process to release a
+// store: --lock-timeout if passed, otherwise the lock.timeout config key.
+func (s *Store) lockTimeout() (time.Duration, error) {
+	if rootCmd.PersistentFlags().Changed("lock-timeout") {
+		return lockTimeoutFlag, nil
+	}
+	v, err := configValue("lock.timeout")
+	if err != nil {
+		return 0, err
+	}
+	return time.ParseDuration(v)
 }
 
-func (s *Store) path(args ...string) (string, error) {
-	scope := gap.NewVendorScope(gap.User, "pda", "stores")
-	dir, err := scope.DataPath("")
+// path returns the directory for the named store, taken from the first root
+// that already holds it. New stores go in the first root. In-memory stores
+// have no directory, so their name stands in for one.
+func (s *Store) path(name string) (string, error) {
+	if isMemStore(name) {
+		return name, nil
+	}
+	roots, err := s.roots()
 	if err != nil {
 		return "", err
 	}
-	if err := os.MkdirAll(dir, 0o750); err != nil {
+	for _, root := range roots {
+		dir := filepath.Join(root.dir, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+	if err := os.MkdirAll(roots[0].dir, 0o750); err != nil {
 		return "", err
 	}
-	return filepath.Join(append([]string{dir}, args...)...), nil
+	return filepath.Join(roots[0].dir, name), nil
 }
 
 func (s *Store) suggestStores(target string) ([]string, error) {
@@ -242,6 +388,59 @@ func (s *Store) suggestStores(target string) ([]string, error) {
 	return suggestions, nil
 }
 
+// confirmDeletes reports whether destructive commands should prompt before
+// acting, per the delete.prompt config key.
+func confirmDeletes() (bool, error) {
+	v, err := configValue("delete.prompt")
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(v)
+}
+
+// promptInput, when set, is where confirmPrompt reads answers instead of the
+// command's stdin. pda shell points it at its own line reader, so a prompt
+// takes the next line of the session rather than racing the shell for stdin.
+var promptInput io.Reader
+
+// confirmPrompt prints message and reports whether the answer is y. It reads
+// a byte at a time so that nothing past the answer's line is consumed.
+func confirmPrompt(cmd *cobra.Command, message string) (bool, error) {
+	fmt.Fprintf(cmd.OutOrStdout(), "%s (y/n)\n", message)
+	in := promptInput
+	if in == nil {
+		in = cmd.InOrStdin()
+	}
+	var answer []byte
+	b := make([]byte, 1)
+	for {
+		n, err := in.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			answer = append(answer, b[0])
+			continue
+		}
+		if errors.Is(err, io.EOF) && len(answer) > 0 {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return strings.EqualFold(strings.TrimSpace(string(answer)), "y"), nil
+}
+
+// nicePath abbreviates the user's home directory to ~ for display.
+func nicePath(path string) string {
+	home, err := os.UserHomeDir()
+	if err == nil && strings.HasPrefix(path, home) {
+		return filepath.Join("~", strings.TrimPrefix(path, home))
+	}
+	return path
+}
+
 func formatExpiry(expiresAt uint64) string {
 	if expiresAt == 0 {
 		return "never"
@@ -0,0 +1,201 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+// shellCmd represents the shell command
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Run pda commands one per line in a single session.",
+	Long: `Run pda commands one per line in a single session.
+
+Each line is a pda command without the leading "pda", such as "set foo bar".
+Words can be quoted with ' or ", and lines starting with # are skipped.
+Values must be given on the line, since stdin is where commands come from.
+A command that asks for confirmation, such as del, takes its answer from
+the next line.
+
+Stores stay open for the session and are closed after --idle-timeout.
+In-memory stores such as @:mem:scratch last until the shell exits, or live
+in the daemon if one is running. Type exit or press Ctrl-D to leave.`,
+	Args: cobra.NoArgs,
+	RunE: shell,
+}
+
+func shell(cmd *cobra.Command, args []string) error {
+	idle, err := cmd.Flags().GetDuration("idle-timeout")
+	if err != nil {
+		return err
+	}
+	if client := dialDaemon(); client != nil {
+		client.Close()
+	} else {
+		activePool = newStorePool(idle)
+		go activePool.reap()
+		defer activePool.closeAll()
+	}
+
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	lines := bufio.NewScanner(cmd.InOrStdin())
+	promptInput = &shellInput{lines: lines}
+	defer func() { promptInput = nil }()
+	for {
+		if interactive {
+			fmt.Fprint(cmd.ErrOrStderr(), "pda> ")
+		}
+		if !lines.Scan() {
+			break
+		}
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words, err := splitWords(line)
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %s\n", err)
+			continue
+		}
+		switch words[0] {
+		case "exit", "quit":
+			return nil
+		case "shell", "daemon", "serve":
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: cannot run %s inside pda shell\n", words[0])
+			continue
+		}
+		runShellLine(words)
+	}
+	if interactive {
+		fmt.Fprintln(cmd.ErrOrStderr())
+	}
+	return lines.Err()
+}
+
+// runShellLine runs one line of a shell session as a fresh pda command.
+// Cobra has already printed any error.
+func runShellLine(words []string) {
+	resetFlags(rootCmd)
+	loadedConfig = nil
+	rootCmd.SetIn(strings.NewReader(""))
+	rootCmd.SetArgs(words)
+	_ = rootCmd.Execute()
+}
+
+// shellInput hands the lines of a shell session to confirmPrompt, one line
+// per Read at most, so that answering a prompt uses up only that line.
+type shellInput struct {
+	lines *bufio.Scanner
+	buf   []byte
+}
+
+func (in *shellInput) Read(p []byte) (int, error) {
+	if len(in.buf) == 0 {
+		if !in.lines.Scan() {
+			if err := in.lines.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		in.buf = append(append(in.buf, in.lines.Bytes()...), '\n')
+	}
+	n := copy(p, in.buf)
+	in.buf = in.buf[n:]
+	return n, nil
+}
+
+// resetFlags puts every flag back to its default so that one shell line
+// does not leak into the next.
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if f.Changed {
+			_ = f.Value.Set(f.DefValue)
+			f.Changed = false
+		}
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// splitWords splits a line into words the way a shell would, honouring
+// single and double quotes and backslash escapes.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("line ends with a backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func init() {
+	shellCmd.Flags().Duration("idle-timeout", 2*time.Second, "close on-disk stores that have not been used for this long")
+	rootCmd.AddCommand(shellCmd)
+}
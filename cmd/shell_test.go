@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestConfirmPromptTakesOneShellLine(t *testing.T) {
+	lines := bufio.NewScanner(strings.NewReader("y\nn\nYes\nlist\n"))
+	promptInput = &shellInput{lines: lines}
+	defer func() { promptInput = nil }()
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	cmd.SetIn(strings.NewReader("y\n"))
+
+	for _, want := range []bool{true, false, false} {
+		got, err := confirmPrompt(cmd, "delete?")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("confirmPrompt() = %v, want %v", got, want)
+		}
+	}
+	if !lines.Scan() || lines.Text() != "list" {
+		t.Errorf("next shell line = %q, want %q", lines.Text(), "list")
+	}
+}
+
+func TestConfirmPromptReadsStdin(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    bool
+		wantErr bool
+	}{
+		{"y\n", true, false},
+		{"Y", true, false},
+		{"n\n", false, false},
+		{"\n", false, false},
+		{"", false, true},
+	}
+	for _, tt := range tests {
+		cmd := &cobra.Command{}
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetIn(strings.NewReader(tt.in))
+		got, err := confirmPrompt(cmd, "delete?")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("confirmPrompt(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+		if out.String() != "delete? (y/n)\n" {
+			t.Errorf("prompt = %q", out.String())
+		}
+	}
+}
@@ -0,0 +1,635 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+const (
+	snapshotsDir  = "snapshots"
+	snapshotExt   = ".snap"
+	snapshotStamp = "20060102T150405.000Z"
+)
+
+var (
+	errNoSnapshots   = errors.New("store does not support snapshots; only badger stores do")
+	errStoreNotEmpty = errors.New("store is not empty")
+	snapshotNameRe   = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+)
+
+// snapshotHeader is the first line of a snapshot file. The rest of the file
+// is the store's badger backup stream. Badger writes backups in plaintext,
+// so the stream of an encrypted store is sealed with a key derived from
+// Salt and the key material in use when the snapshot was taken.
+type snapshotHeader struct {
+	Store   string    `json:"store"`
+	Name    string    `json:"name,omitempty"`
+	Created time.Time `json:"created"`
+	Version string    `json:"pda_version"`
+	Meta    storeMeta `json:"meta"`
+	Salt    []byte    `json:"salt,omitempty"`
+}
+
+// snapshot is a snapshot file on disk. Its ID is the file name without the
+// extension: the creation time, then the name if it has one.
+type snapshot struct {
+	snapshotHeader
+	ID   string
+	Path string
+	Size int64
+}
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore whole dbs.",
+	Long: `Save and restore whole dbs, history included.
+
+Snapshots are kept in a snapshots directory beside the stores directory
+holding the db (.pda/snapshots for a local store, $PDA_HOME/snapshots if
+set), so they outlive delete-db. Only the newest
+snapshot.retention snapshots of each db are kept. One is also taken
+automatically before restore writes into a db that has keys, before
+delete-db, and before snapshot restore; snapshot.retention 0 turns the
+automatic ones off.
+
+Snapshots of an encrypted db are encrypted too, with the key in use when
+they were taken; after a rekey, restoring an older snapshot needs the old
+passphrase or keyfile.`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:         "create [DB]",
+	Short:       "Snapshot a db.",
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: writesData,
+	RunE:        snapshotCreate,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list [DB]",
+	Short: "List the snapshots of a db, newest first.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  snapshotList,
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore [DB]",
+	Short: "Replace a db with one of its snapshots.",
+	Long: `Replace a db with one of its snapshots, the newest unless --from names
+another by ID or name. The db is recreated if it has been deleted.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: writesData,
+	RunE:        snapshotRestore,
+}
+
+var snapshotPruneCmd = &cobra.Command{
+	Use:         "prune [DB]",
+	Short:       "Delete all but the newest snapshots of a db.",
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: writesData,
+	RunE:        snapshotPrune,
+}
+
+func snapshotCreate(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	dbName, err := existingDB(store, args)
+	if err != nil {
+		return err
+	}
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return err
+	}
+	if name != "" && !snapshotNameRe.MatchString(name) {
+		return fmt.Errorf("snapshot names may only use a-z, 0-9, '.', '_' and '-'")
+	}
+	db, err := store.openReadOnly(dbName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	snap, err := createSnapshot(store, db, dbName, name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Saved snapshot %s of @%s (%s)\n", snap.ID, dbName, formatSize(snap.Size))
+	keep, err := snapshotRetention()
+	if err != nil || keep == 0 {
+		return err
+	}
+	return pruneSnapshots(cmd, store, dbName, keep)
+}
+
+func snapshotList(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	dbName := defaultDB()
+	if len(args) == 1 {
+		parsed, err := store.parseDB(args[0], false)
+		if err != nil {
+			return err
+		}
+		dbName = parsed
+	}
+	snaps, err := listSnapshots(store, dbName)
+	if err != nil {
+		return err
+	}
+	tw := table.NewWriter()
+	tw.SetOutputMirror(cmd.OutOrStdout())
+	tw.SetStyle(table.StyleLight)
+	tw.AppendHeader(table.Row{"ID", "Created", "Size", "pda"})
+	for _, snap := range snaps {
+		tw.AppendRow(table.Row{snap.ID, snap.Created.UTC().Format(time.RFC3339), formatSize(snap.Size), snap.Version})
+	}
+	tw.Render()
+	return nil
+}
+
+func snapshotRestore(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	dbName := defaultDB()
+	if len(args) == 1 {
+		parsed, err := store.parseDB(args[0], false)
+		if err != nil {
+			return err
+		}
+		dbName = parsed
+	}
+	from, err := cmd.Flags().GetString("from")
+	if err != nil {
+		return err
+	}
+	noSnapshot, err := cmd.Flags().GetBool("no-snapshot")
+	if err != nil {
+		return err
+	}
+	snaps, err := listSnapshots(store, dbName)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(snaps, func(s snapshot) bool {
+		return from == "" || s.ID == from || s.Name == from
+	})
+	if i < 0 && from == "" {
+		return fmt.Errorf("@%s has no snapshots", dbName)
+	}
+	if i < 0 {
+		return fmt.Errorf("@%s has no snapshot %q; see pda snapshot list %s", dbName, from, dbName)
+	}
+	snap := snaps[i]
+
+	// Open the snapshot before taking a safety one, whose pruning may
+	// remove it.
+	f, err := os.Open(snap.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	header, err := readSnapshotHeader(br)
+	if err != nil {
+		return err
+	}
+	r, err := snapshotBody(br, header)
+	if err != nil {
+		return err
+	}
+
+	path, err := store.path(dbName)
+	if err != nil {
+		return err
+	}
+	exists := isMemStore(dbName)
+	if !exists {
+		_, err := os.Stat(path)
+		exists = err == nil
+	}
+	if exists {
+		meta, err := snapshotMeta(store, dbName)
+		if err != nil {
+			return err
+		}
+		if backendName(meta) != backendName(snap.Meta) {
+			return fmt.Errorf("@%s is now a %s store and the snapshot is of a %s store; delete it first", dbName, backendName(meta), backendName(snap.Meta))
+		}
+		if !noSnapshot {
+			if err := safetySnapshot(cmd, store, dbName, "before-snapshot-restore"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !isMemStore(dbName) {
+		if err := releaseStore(path); err != nil {
+			return err
+		}
+		// A store that still exists keeps its own meta: its encryption
+		// salt may have changed with rekey since the snapshot was taken.
+		// Only a deleted store is recreated from the snapshot's meta.
+		if !exists {
+			if err := os.MkdirAll(path, 0o750); err != nil {
+				return err
+			}
+			if err := writeStoreMeta(path, snap.Meta); err != nil {
+				return err
+			}
+		}
+	}
+	db, err := store.open(dbName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	s, ok := capability[snapshotter](db)
+	if !ok {
+		return errNoSnapshots
+	}
+	if err := s.Replace(r); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Restored @%s from snapshot %s\n", dbName, snap.ID)
+	return nil
+}
+
+func snapshotPrune(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	dbName := defaultDB()
+	if len(args) == 1 {
+		parsed, err := store.parseDB(args[0], false)
+		if err != nil {
+			return err
+		}
+		dbName = parsed
+	}
+	keep, err := cmd.Flags().GetInt("keep")
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("keep") {
+		if keep, err = snapshotRetention(); err != nil {
+			return err
+		}
+	}
+	if keep < 0 {
+		return fmt.Errorf("--keep must be 0 or more")
+	}
+	return pruneSnapshots(cmd, store, dbName, keep)
+}
+
+// existingDB resolves an optional DB argument to a store that exists.
+func existingDB(store *Store, args []string) (string, error) {
+	if len(args) == 0 {
+		return defaultDB(), nil
+	}
+	dbName, err := store.parseDB(args[0], false)
+	if err != nil {
+		return "", err
+	}
+	if _, err := store.FindStore(dbName); err != nil {
+		var notFound errNotFound
+		if errors.As(err, &notFound) {
+			return "", fmt.Errorf("%q does not exist, %s", args[0], err.Error())
+		}
+		return "", err
+	}
+	return dbName, nil
+}
+
+// safetySnapshot snapshots dbName ahead of a command that would destroy its
+// contents, unless snapshot.retention is 0 or the db is empty. Stores that
+// cannot be snapshotted only get a warning.
+func safetySnapshot(cmd *cobra.Command, store *Store, dbName, reason string) error {
+	keep, err := snapshotRetention()
+	if err != nil || keep == 0 {
+		return err
+	}
+	db, err := store.openReadOnly(dbName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	empty, err := storeEmpty(db)
+	if err != nil || empty {
+		return err
+	}
+	if _, ok := capability[snapshotter](db); !ok {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Not snapshotting @%s first: %s\n", dbName, errNoSnapshots)
+		return nil
+	}
+	snap, err := createSnapshot(store, db, dbName, reason)
+	if err != nil {
+		return fmt.Errorf("cannot snapshot @%s first: %w; pass --no-snapshot to go ahead anyway", dbName, err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Saved snapshot %s of @%s; pda snapshot restore %s --from %s puts it back\n", snap.ID, dbName, dbName, snap.ID)
+	return pruneSnapshots(cmd, store, dbName, keep)
+}
+
+// createSnapshot writes a snapshot of db to a temporary file and renames it
+// into place once complete.
+func createSnapshot(store *Store, db Backend, dbName, name string) (snapshot, error) {
+	s, ok := capability[snapshotter](db)
+	if !ok {
+		return snapshot{}, errNoSnapshots
+	}
+	meta, err := snapshotMeta(store, dbName)
+	if err != nil {
+		return snapshot{}, err
+	}
+	dir, err := snapshotDir(store, dbName)
+	if err != nil {
+		return snapshot{}, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return snapshot{}, err
+	}
+	header := snapshotHeader{
+		Store:   dbName,
+		Name:    name,
+		Created: time.Now().UTC(),
+		Version: pdaVersion(),
+		Meta:    meta,
+	}
+	var key []byte
+	if meta.Encrypted {
+		material, err := loadKeyMaterial()
+		if err != nil {
+			return snapshot{}, err
+		}
+		if header.Salt, err = newSalt(); err != nil {
+			return snapshot{}, err
+		}
+		key = deriveKey(material, header.Salt)
+	}
+	id := header.Created.Format(snapshotStamp)
+	if name != "" {
+		id += "-" + name
+	}
+	path := filepath.Join(dir, id+snapshotExt)
+
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return snapshot{}, err
+	}
+	defer os.Remove(f.Name())
+	w := bufio.NewWriter(f)
+	line, err := json.Marshal(header)
+	if err != nil {
+		f.Close()
+		return snapshot{}, err
+	}
+	w.Write(append(line, '\n'))
+	if err := writeSnapshotBody(w, s, key); err != nil {
+		f.Close()
+		return snapshot{}, err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return snapshot{}, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return snapshot{}, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return snapshot{}, err
+	}
+	if err := f.Close(); err != nil {
+		return snapshot{}, err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return snapshot{}, err
+	}
+	return snapshot{snapshotHeader: header, ID: id, Path: path, Size: info.Size()}, nil
+}
+
+// writeSnapshotBody writes the backup stream of s to w, sealed under key if
+// there is one.
+func writeSnapshotBody(w io.Writer, s snapshotter, key []byte) error {
+	if key == nil {
+		_, err := s.Backup(w, 0)
+		return err
+	}
+	sw, err := newSealWriter(w, key)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Backup(sw, 0); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+// snapshotBody returns the backup stream that follows header in r,
+// decrypting it if the snapshot is sealed.
+func snapshotBody(r io.Reader, header snapshotHeader) (io.Reader, error) {
+	if header.Salt == nil {
+		return r, nil
+	}
+	material, err := loadKeyMaterial()
+	if err != nil {
+		return nil, err
+	}
+	sr, err := newSealReader(r, deriveKey(material, header.Salt))
+	if err != nil {
+		return nil, err
+	}
+	// Check the key on the first frame, before the store is emptied.
+	if err := sr.next(); err != nil {
+		return nil, fmt.Errorf("cannot open snapshot: %w", err)
+	}
+	return sr, nil
+}
+
+// listSnapshots returns the snapshots of dbName, newest first.
+func listSnapshots(store *Store, dbName string) ([]snapshot, error) {
+	dir, err := snapshotDir(store, dbName)
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snaps []snapshot
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), snapshotExt) {
+			continue
+		}
+		snap, err := readSnapshot(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	slices.SortFunc(snaps, func(a, b snapshot) int {
+		return b.Created.Compare(a.Created)
+	})
+	return snaps, nil
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return snapshot{}, err
+	}
+	defer f.Close()
+	header, err := readSnapshotHeader(bufio.NewReader(f))
+	if err != nil {
+		return snapshot{}, fmt.Errorf("%s: %w", nicePath(path), err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return snapshot{}, err
+	}
+	id := strings.TrimSuffix(filepath.Base(path), snapshotExt)
+	return snapshot{snapshotHeader: header, ID: id, Path: path, Size: info.Size()}, nil
+}
+
+func readSnapshotHeader(r *bufio.Reader) (snapshotHeader, error) {
+	var header snapshotHeader
+	line, err := r.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return header, err
+	}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return header, fmt.Errorf("not a pda snapshot: %w", err)
+	}
+	return header, nil
+}
+
+// pruneSnapshots deletes all but the newest keep snapshots of dbName.
+func pruneSnapshots(cmd *cobra.Command, store *Store, dbName string, keep int) error {
+	snaps, err := listSnapshots(store, dbName)
+	if err != nil {
+		return err
+	}
+	if len(snaps) <= keep {
+		return nil
+	}
+	for _, snap := range snaps[keep:] {
+		if err := os.Remove(snap.Path); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Pruned %d snapshots of @%s\n", len(snaps)-keep, dbName)
+	return nil
+}
+
+// snapshotDir returns the directory holding the snapshots of dbName. They
+// sit beside the store root holding dbName, so stores of the same name in
+// different roots keep apart: .pda/snapshots/DB for .pda/stores/DB, and
+// likewise under $PDA_HOME or the user data directory. A --store-dir not
+// named stores keeps them in its own .snapshots/DB.
+func snapshotDir(store *Store, dbName string) (string, error) {
+	path, err := store.path(dbName)
+	if err != nil {
+		return "", err
+	}
+	root := filepath.Dir(path)
+	if isMemStore(dbName) {
+		roots, err := store.roots()
+		if err != nil {
+			return "", err
+		}
+		root = roots[0].dir
+	}
+	if filepath.Base(root) == storesDir {
+		return filepath.Join(filepath.Dir(root), snapshotsDir, dbName), nil
+	}
+	return filepath.Join(root, "."+snapshotsDir, dbName), nil
+}
+
+func snapshotRetention() (int, error) {
+	v, err := configValue("snapshot.retention")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("snapshot.retention: %w", err)
+	}
+	return n, nil
+}
+
+func snapshotMeta(store *Store, dbName string) (storeMeta, error) {
+	if isMemStore(dbName) {
+		return storeMeta{Backend: backendMemory}, nil
+	}
+	path, err := store.path(dbName)
+	if err != nil {
+		return storeMeta{}, err
+	}
+	return readStoreMeta(path)
+}
+
+func backendName(meta storeMeta) string {
+	if meta.Backend == "" {
+		return backendBadger
+	}
+	return meta.Backend
+}
+
+// storeEmpty reports whether db holds no keys of its own.
+func storeEmpty(db Backend) (bool, error) {
+	tx, err := db.NewTx(false)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Discard()
+	err = tx.Iterate(IterOptions{}, func(e Entry) error {
+		return errStoreNotEmpty
+	})
+	if errors.Is(err, errStoreNotEmpty) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func init() {
+	snapshotCreateCmd.Flags().StringP("name", "n", "", "name the snapshot, so it can be restored by name")
+	snapshotRestoreCmd.Flags().String("from", "", "ID or name of the snapshot to restore (default the newest)")
+	snapshotRestoreCmd.Flags().Bool("no-snapshot", false, "do not snapshot the db before replacing it")
+	snapshotPruneCmd.Flags().Int("keep", 0, "snapshots to keep (default from snapshot.retention)")
+	snapshotCmd.AddCommand(snapshotCreateCmd, snapshotListCmd, snapshotRestoreCmd, snapshotPruneCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
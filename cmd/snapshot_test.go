@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotOfEncryptedStoreIsSealed(t *testing.T) {
+	t.Setenv(envHome, t.TempDir())
+	t.Setenv(envPassphrase, "hunter2")
+	t.Setenv(envNoDaemon, "1")
+	keyMaterial, loadedConfig = nil, nil
+	defer func() { keyMaterial, loadedConfig = nil, nil }()
+
+	store := &Store{}
+	path, err := store.path("vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt, _ := newSalt()
+	meta := storeMeta{Encrypted: true, Salt: salt}
+	if err := os.MkdirAll(path, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeStoreMeta(path, meta); err != nil {
+		t.Fatal(err)
+	}
+	db, err := openBadger(path, meta, deriveKey([]byte("hunter2"), salt), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, _ := db.NewTx(true)
+	secret := []byte("plaintext-that-must-not-leak")
+	if err := tx.Set(Entry{Key: []byte("k"), Value: secret}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := createSnapshot(store, db, "vault", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(snap.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, secret) {
+		t.Fatal("snapshot of an encrypted store holds a value in plaintext")
+	}
+
+	f, err := os.Open(snap.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	header, err := readSnapshotHeader(br)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := snapshotBody(br, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem, err := openBadgerMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mem.Close()
+	if err := mem.Load(body); err != nil {
+		t.Fatal(err)
+	}
+	tx, _ = mem.NewTx(false)
+	defer tx.Discard()
+	e, err := tx.Get([]byte("k"))
+	if err != nil || !bytes.Equal(e.Value, secret) {
+		t.Errorf("restored k = %q, %v", e.Value, err)
+	}
+}
+
+func TestSnapshotDirFollowsStoreRoot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(envHome, home)
+	defer func(dir string) { storeDir = dir }(storeDir)
+	flagged := t.TempDir()
+
+	tests := []struct {
+		name     string
+		storeDir string
+		want     string
+	}{
+		{"home", "", filepath.Join(home, snapshotsDir, "db")},
+		{"store-dir", flagged, filepath.Join(flagged, "."+snapshotsDir, "db")},
+		{"store-dir named stores", filepath.Join(flagged, storesDir), filepath.Join(flagged, snapshotsDir, "db")},
+	}
+	for _, tt := range tests {
+		storeDir = tt.storeDir
+		got, err := snapshotDir(&Store{}, "db")
+		if err != nil || got != tt.want {
+			t.Errorf("%s: snapshotDir() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats [DB]",
+	Short: "Show how many keys a db holds and how much space they take.",
+	Long: `Show how many keys a db holds and how much space they take.
+
+Reports key, secret and expiring counts, value sizes, and the size of the
+store on disk. --by-prefix instead breaks the keys down by prefix, like du,
+splitting keys on --separator and grouping them --depth levels deep.
+
+Internal counts the entries pda keeps beside the keys themselves: the
+elements of lists, hashes and sets, history times, the undo journal and
+trash, and labels and notes. With --by-prefix they are one (internal) row.
+
+--format prometheus writes every figure, prefixes included, in the
+Prometheus text format for the node exporter's textfile collector.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: stats,
+}
+
+type storeStats struct {
+	name       string
+	keys       int
+	secrets    int
+	expiring   int
+	valueBytes int64
+	internal   prefixStats
+	disk       *DiskUsage
+	prefixes   []prefixStats
+}
+
+type prefixStats struct {
+	prefix string
+	keys   int
+	bytes  int64
+}
+
+func stats(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	flags := cmd.Flags()
+	all, err := flags.GetBool("all")
+	if err != nil {
+		return err
+	}
+	byPrefix, err := flags.GetBool("by-prefix")
+	if err != nil {
+		return err
+	}
+	separator, err := flags.GetString("separator")
+	if err != nil {
+		return err
+	}
+	depth, err := flags.GetInt("depth")
+	if err != nil {
+		return err
+	}
+	noHeader, err := flags.GetBool("no-header")
+	if err != nil {
+		return err
+	}
+	outFormat, err := flags.GetString("format")
+	if err != nil {
+		return err
+	}
+	var tableFormat formatEnum
+	if outFormat != "prometheus" {
+		if err := tableFormat.Set(outFormat); err != nil {
+			return fmt.Errorf("must be one of \"table\", \"csv\", \"html\", \"markdown\", or \"prometheus\"")
+		}
+	}
+	if all && len(args) > 0 {
+		return fmt.Errorf("cannot use --all with a db")
+	}
+	if depth < 1 {
+		return fmt.Errorf("--depth must be at least 1")
+	}
+
+	dbs := []string{"@" + defaultDB()}
+	if len(args) == 1 {
+		dbs = args
+	}
+	if all {
+		if dbs, err = store.AllStores(); err != nil {
+			return err
+		}
+	}
+
+	var results []storeStats
+	for _, rawArg := range dbs {
+		name, err := store.parseDB(rawArg, false)
+		if err != nil {
+			return err
+		}
+		if _, err := store.FindStore(name); err != nil {
+			var notFound errNotFound
+			if errors.As(err, &notFound) {
+				return fmt.Errorf("%q does not exist, %s", rawArg, err.Error())
+			}
+			return err
+		}
+		st, err := collectStats(store, name, separator, depth)
+		if err != nil {
+			return err
+		}
+		results = append(results, st)
+	}
+
+	out := cmd.OutOrStdout()
+	if outFormat == "prometheus" {
+		writePrometheus(out, results)
+		return nil
+	}
+
+	size := formatSize
+	if tableFormat == "csv" {
+		size = func(n int64) string { return strconv.FormatInt(n, 10) }
+	}
+	tw := table.NewWriter()
+	tw.SetOutputMirror(out)
+	tw.SetStyle(table.StyleLight)
+	if byPrefix {
+		if !noHeader {
+			tw.AppendHeader(table.Row{"DB", "Prefix", "Keys", "Size"})
+		}
+		for _, st := range results {
+			for _, p := range st.prefixes {
+				prefix := p.prefix
+				if prefix == "" {
+					prefix = "(none)"
+				}
+				tw.AppendRow(table.Row{"@" + st.name, prefix, p.keys, size(p.bytes)})
+			}
+			if st.internal.keys > 0 {
+				tw.AppendRow(table.Row{"@" + st.name, "(internal)", st.internal.keys, size(st.internal.bytes)})
+			}
+		}
+	} else {
+		if !noHeader {
+			tw.AppendHeader(table.Row{"DB", "Keys", "Secrets", "Expiring", "Values", "Avg value", "Internal", "LSM", "Vlog", "Tables", "Disk"})
+		}
+		for _, st := range results {
+			avg := int64(0)
+			if st.keys > 0 {
+				avg = st.valueBytes / int64(st.keys)
+			}
+			row := table.Row{"@" + st.name, st.keys, st.secrets, st.expiring, size(st.valueBytes), size(avg), size(st.internal.bytes)}
+			switch {
+			case st.disk == nil:
+				row = append(row, "-", "-", "-", "-")
+			case st.disk.LSM == 0 && st.disk.VLog == 0 && st.disk.Tables == 0:
+				row = append(row, "-", "-", "-", size(st.disk.Total))
+			default:
+				row = append(row, size(st.disk.LSM), size(st.disk.VLog), st.disk.Tables, size(st.disk.Total))
+			}
+			tw.AppendRow(row)
+		}
+	}
+	tableFormat.renderer()(tw)
+	return nil
+}
+
+// collectStats reads every entry of the named store, grouping keys by their
+// first depth segments. Internal entries are totalled on their own.
+func collectStats(store *Store, name, separator string, depth int) (storeStats, error) {
+	st := storeStats{name: name}
+	db, err := store.openReadOnly(name)
+	if err != nil {
+		return st, err
+	}
+	defer db.Close()
+
+	tx, err := db.NewTx(false)
+	if err != nil {
+		return st, err
+	}
+	defer tx.Discard()
+	groups := map[string]*prefixStats{}
+	err = tx.Iterate(IterOptions{Values: true}, func(e Entry) error {
+		st.keys++
+		if e.Meta&metaSecret != 0 {
+			st.secrets++
+		}
+		if e.ExpiresAt != 0 {
+			st.expiring++
+		}
+		st.valueBytes += int64(len(e.Value))
+
+		prefix := keyPrefix(string(e.Key), separator, depth)
+		g, ok := groups[prefix]
+		if !ok {
+			g = &prefixStats{prefix: prefix}
+			groups[prefix] = g
+		}
+		g.keys++
+		g.bytes += int64(len(e.Key) + len(e.Value))
+		return nil
+	})
+	if err != nil {
+		return st, err
+	}
+	err = tx.Iterate(IterOptions{Prefix: []byte(internalPrefix), Values: true}, func(e Entry) error {
+		st.internal.keys++
+		st.internal.bytes += int64(len(e.Key) + len(e.Value))
+		return nil
+	})
+	if err != nil {
+		return st, err
+	}
+
+	for _, g := range groups {
+		st.prefixes = append(st.prefixes, *g)
+	}
+	slices.SortFunc(st.prefixes, func(a, b prefixStats) int {
+		if c := cmp.Compare(b.bytes, a.bytes); c != 0 {
+			return c
+		}
+		return strings.Compare(a.prefix, b.prefix)
+	})
+
+	if s, ok := capability[sizer](db); ok {
+		usage, err := s.DiskUsage()
+		if err != nil {
+			return st, err
+		}
+		st.disk = &usage
+	}
+	return st, nil
+}
+
+// keyPrefix returns up to depth leading segments of key, each ending in
+// separator. Keys without a separator have no prefix.
+func keyPrefix(key, separator string, depth int) string {
+	if separator == "" {
+		return ""
+	}
+	parts := strings.SplitAfterN(key, separator, depth+1)
+	return strings.Join(parts[:min(depth, len(parts)-1)], "")
+}
+
+// writePrometheus writes stats in the Prometheus text exposition format.
+func writePrometheus(w io.Writer, results []storeStats) {
+	labels := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	metric := func(name, help string, value func(st storeStats) (int64, bool)) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, st := range results {
+			if v, ok := value(st); ok {
+				fmt.Fprintf(w, "%s{db=\"%s\"} %d\n", name, labels.Replace(st.name), v)
+			}
+		}
+	}
+	count := func(f func(st storeStats) int) func(storeStats) (int64, bool) {
+		return func(st storeStats) (int64, bool) { return int64(f(st)), true }
+	}
+	disk := func(f func(d DiskUsage) int64) func(storeStats) (int64, bool) {
+		return func(st storeStats) (int64, bool) {
+			if st.disk == nil {
+				return 0, false
+			}
+			return f(*st.disk), true
+		}
+	}
+
+	metric("pda_keys", "Number of keys in the store.", count(func(st storeStats) int { return st.keys }))
+	metric("pda_secret_keys", "Number of keys marked secret.", count(func(st storeStats) int { return st.secrets }))
+	metric("pda_expiring_keys", "Number of keys with a TTL.", count(func(st storeStats) int { return st.expiring }))
+	metric("pda_value_bytes", "Total size of all values.", func(st storeStats) (int64, bool) { return st.valueBytes, true })
+	metric("pda_internal_entries", "Number of entries pda keeps for collections, history, undo and labels.", count(func(st storeStats) int { return st.internal.keys }))
+	metric("pda_internal_bytes", "Size of those internal entries.", func(st storeStats) (int64, bool) { return st.internal.bytes, true })
+	metric("pda_disk_bytes", "Size of the store on disk.", disk(func(d DiskUsage) int64 { return d.Total }))
+	metric("pda_lsm_bytes", "Size of the LSM tree on disk.", disk(func(d DiskUsage) int64 { return d.LSM }))
+	metric("pda_vlog_bytes", "Size of the value log on disk.", disk(func(d DiskUsage) int64 { return d.VLog }))
+
+	for _, m := range []struct {
+		name, help string
+		value      func(p prefixStats) int64
+	}{
+		{"pda_prefix_keys", "Number of keys under a prefix.", func(p prefixStats) int64 { return int64(p.keys) }},
+		{"pda_prefix_bytes", "Size of the keys and values under a prefix.", func(p prefixStats) int64 { return p.bytes }},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, st := range results {
+			for _, p := range st.prefixes {
+				fmt.Fprintf(w, "%s{db=\"%s\",prefix=\"%s\"} %d\n", m.name, labels.Replace(st.name), labels.Replace(p.prefix), m.value(p))
+			}
+		}
+	}
+}
+
+func init() {
+	statsCmd.Flags().Bool("all", false, "show every db")
+	statsCmd.Flags().BoolP("by-prefix", "p", false, "break keys down by prefix instead")
+	statsCmd.Flags().String("separator", "/", "separator between key segments for --by-prefix")
+	statsCmd.Flags().Int("depth", 1, "number of key segments to group by for --by-prefix")
+	statsCmd.Flags().Bool("no-header", false, "omit the header row")
+	statsCmd.Flags().StringP("format", "o", "table", "render output format (table|csv|markdown|html|prometheus)")
+	rootCmd.AddCommand(statsCmd)
+}
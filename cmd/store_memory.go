@@ -0,0 +1,56 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// memPrefix marks in-memory stores, as in KEY@:mem:NAME. They are created on
+// first use and vanish when the daemon, server or shell holding them exits.
+const memPrefix = ":mem:"
+
+var errNoMemoryHost = errors.New("in-memory stores only live inside pda daemon, pda serve or pda shell; start one first")
+
+func isMemStore(name string) bool {
+	return strings.HasPrefix(name, memPrefix) && len(name) > len(memPrefix)
+}
+
+// memStores lists the in-memory stores held by this process or the daemon.
+func memStores() ([]string, error) {
+	if activePool != nil {
+		return activePool.memStores(), nil
+	}
+	client := dialDaemon()
+	if client == nil {
+		return nil, nil
+	}
+	defer client.Close()
+	var names []string
+	if err := remoteError(client.Call("Daemon.MemStores", DaemonArgs{}, &names)); err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	return names, nil
+}
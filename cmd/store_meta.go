@@ -0,0 +1,77 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const storeMetaFile = "pda.json"
+
+// storeMeta holds per-store settings chosen when the store was created.
+// Versions is how many versions of each key a badger store keeps.
+type storeMeta struct {
+	Backend   string `json:"backend,omitempty"`
+	Encrypted bool   `json:"encrypted,omitempty"`
+	Salt      []byte `json:"salt,omitempty"`
+	Versions  int    `json:"versions,omitempty"`
+}
+
+func readStoreMeta(dir string) (storeMeta, error) {
+	var meta storeMeta
+	b, err := os.ReadFile(filepath.Join(dir, storeMetaFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return meta, err
+	}
+	return meta, nil
+}
+
+func writeStoreMeta(dir string, meta storeMeta) error {
+	tmp, err := stageStoreMeta(dir, meta)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, storeMetaFile))
+}
+
+// stageStoreMeta writes meta to a temporary file beside the store's meta and
+// returns its path, for the caller to rename into place.
+func stageStoreMeta(dir string, meta storeMeta) (string, error) {
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(dir, storeMetaFile+".tmp")
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return "", err
+	}
+	return tmp, nil
+}
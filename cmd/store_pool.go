@@ -0,0 +1,202 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// activePool, when set, holds the stores of a long-running process such as
+// the daemon or a server. Store.open borrows from it instead of opening and
+// closing a store for every command.
+var activePool *storePool
+
+// storePool keeps stores open between uses and closes them once they have
+// sat idle for a while.
+type storePool struct {
+	mu     sync.Mutex
+	stores map[string]*pooledStore
+	idle   time.Duration
+}
+
+// pooledStore is one store in the pool. ready is closed once the store has
+// been opened, or has failed to open with err; db and err are only read after
+// that.
+type pooledStore struct {
+	db       Backend
+	err      error
+	key      []byte
+	ready    chan struct{}
+	refs     int
+	lastUsed time.Time
+}
+
+func newStorePool(idle time.Duration) *storePool {
+	return &storePool{stores: map[string]*pooledStore{}, idle: idle}
+}
+
+// acquire returns the open store at path, opening it if needed. Every
+// acquire must be paired with a release. The pool lock is only held to find
+// or add the store's entry, so waiting on a store locked by another process
+// does not hold up requests for other stores.
+func (p *storePool) acquire(name, path string, meta storeMeta, key []byte, timeout time.Duration) (Backend, error) {
+	p.mu.Lock()
+	s, ok := p.stores[path]
+	if !ok {
+		s = &pooledStore{key: key, ready: make(chan struct{})}
+		p.stores[path] = s
+	}
+	s.refs++
+	p.mu.Unlock()
+
+	if !ok {
+		db, err := waitOpen(name, path, meta, key, false, timeout)
+		p.mu.Lock()
+		s.db, s.err = db, err
+		s.lastUsed = time.Now()
+		if err != nil && p.stores[path] == s {
+			delete(p.stores, path)
+		}
+		p.mu.Unlock()
+		close(s.ready)
+	}
+	<-s.ready
+	if s.err != nil {
+		return nil, s.err
+	}
+	if subtle.ConstantTimeCompare(s.key, key) != 1 {
+		p.release(path)
+		return nil, errWrongKey
+	}
+	p.mu.Lock()
+	s.lastUsed = time.Now()
+	p.mu.Unlock()
+	return s.db, nil
+}
+
+func (p *storePool) release(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, ok := p.stores[path]; ok {
+		s.refs--
+		s.lastUsed = time.Now()
+	}
+}
+
+func (p *storePool) get(path string) (Backend, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.stores[path]
+	if !ok {
+		return nil, fmt.Errorf("store %s was released", path)
+	}
+	return s.db, nil
+}
+
+// evict closes the store at path so it can be changed on disk.
+func (p *storePool) evict(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.stores[path]
+	if !ok {
+		return nil
+	}
+	if s.refs > 0 {
+		return fmt.Errorf("%s is in use by another pda command", nicePath(path))
+	}
+	delete(p.stores, path)
+	return s.db.Close()
+}
+
+// open is acquire for callers that want a Backend whose Close hands the
+// store back to the pool.
+func (p *storePool) open(name, path string, meta storeMeta, key []byte, timeout time.Duration) (Backend, error) {
+	db, err := p.acquire(name, path, meta, key, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledBackend{Backend: db, pool: p, path: path}, nil
+}
+
+// memStores returns the names of the in-memory stores in the pool.
+func (p *storePool) memStores() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var names []string
+	for path := range p.stores {
+		if isMemStore(path) {
+			names = append(names, path)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// reap closes stores that nobody has used for the idle timeout. In-memory
+// stores are kept, since closing one would lose it.
+func (p *storePool) reap() {
+	ticker := time.NewTicker(max(p.idle/4, time.Second))
+	defer ticker.Stop()
+	for range ticker.C {
+		p.mu.Lock()
+		for path, s := range p.stores {
+			if isMemStore(path) {
+				continue
+			}
+			if s.refs == 0 && time.Since(s.lastUsed) >= p.idle {
+				s.db.Close()
+				delete(p.stores, path)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *storePool) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for path, s := range p.stores {
+		if s.db != nil {
+			s.db.Close()
+		}
+		delete(p.stores, path)
+	}
+}
+
+type pooledBackend struct {
+	Backend
+	pool *storePool
+	path string
+}
+
+func (b *pooledBackend) Close() error {
+	b.pool.release(b.path)
+	return nil
+}
+
+func (b *pooledBackend) Unwrap() Backend {
+	return b.Backend
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStorePoolAcquireDoesNotBlockOtherStores(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("badger does not flock the store directory on windows")
+	}
+	root := t.TempDir()
+	locked, free := filepath.Join(root, "locked"), filepath.Join(root, "free")
+	for _, dir := range []string{locked, free} {
+		db, err := openBadger(dir, storeMeta{}, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+	}
+	unlock, err := lockStoreDir(locked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := newStorePool(time.Minute)
+	defer p.closeAll()
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = p.acquire("locked", locked, storeMeta{}, nil, 500*time.Millisecond)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := p.acquire("free", free, storeMeta{}, nil, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 250*time.Millisecond {
+		t.Errorf("acquiring a free store took %s while another was locked", d)
+	}
+	p.release(free)
+
+	wg.Wait()
+	unlock()
+	for _, err := range errs {
+		if !errors.As(err, new(errLockTimeout)) {
+			t.Errorf("acquire of locked store = %v, want errLockTimeout", err)
+		}
+	}
+	if _, err := p.acquire("locked", locked, storeMeta{}, nil, time.Second); err != nil {
+		t.Fatalf("acquire after unlock: %v", err)
+	}
+	p.release(locked)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	gap "github.com/muesli/go-app-paths"
+)
+
+const (
+	envHome     = "PDA_HOME"
+	localDir    = ".pda"
+	storesDir   = "stores"
+	rootLocal   = "local"
+	rootHome    = "home"
+	rootUser    = "user"
+	rootFlagged = "store-dir"
+)
+
+var storeDir string
+
+// storeRoot is a directory that holds stores. Roots are searched in order,
+// so a store in an earlier root shadows one of the same name in a later one.
+type storeRoot struct {
+	kind string
+	dir  string
+}
+
+// roots returns the store roots in lookup order. --store-dir replaces every
+// other root; otherwise the nearest .pda/ directory above the working
+// directory is searched before $PDA_HOME or the user data directory.
+func (s *Store) roots() ([]storeRoot, error) {
+	if storeDir != "" {
+		dir, err := filepath.Abs(storeDir)
+		if err != nil {
+			return nil, err
+		}
+		return []storeRoot{{kind: rootFlagged, dir: dir}}, nil
+	}
+
+	user, err := userRoot()
+	if err != nil {
+		return nil, err
+	}
+	roots := make([]storeRoot, 0, 2)
+	if local, ok := findLocalRoot(); ok && local != user.dir {
+		roots = append(roots, storeRoot{kind: rootLocal, dir: local})
+	}
+	return append(roots, user), nil
+}
+
+func userRoot() (storeRoot, error) {
+	if home := os.Getenv(envHome); home != "" {
+		dir, err := filepath.Abs(filepath.Join(home, storesDir))
+		if err != nil {
+			return storeRoot{}, err
+		}
+		return storeRoot{kind: rootHome, dir: dir}, nil
+	}
+	scope := gap.NewVendorScope(gap.User, "pda", storesDir)
+	dir, err := scope.DataPath("")
+	if err != nil {
+		return storeRoot{}, err
+	}
+	return storeRoot{kind: rootUser, dir: dir}, nil
+}
+
+// findLocalRoot walks up from the working directory looking for a .pda/
+// directory, the same way git finds .git/.
+func findLocalRoot() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for {
+		candidate := filepath.Join(dir, localDir)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return filepath.Join(candidate, storesDir), true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+func storesIn(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stores []string
+	for _, e := range entries {
+		// Dot directories, such as a --store-dir's .snapshots, are not
+		// stores.
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			stores = append(stores, e.Name())
+		}
+	}
+	return stores, nil
+}
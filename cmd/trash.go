@@ -0,0 +1,219 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// trashCmd represents the trash command
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Recover deleted keys.",
+	Long: `Recover deleted keys.
+
+Deleted keys are kept in their db's trash for trash.retention (a week by
+default), with their secret flag and the TTL they had left.`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list [DB]",
+	Short: "List the keys in a db's trash.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  trashList,
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:         "restore KEY[@DB]",
+	Short:       "Put a deleted key back.",
+	Args:        cobra.ExactArgs(1),
+	Annotations: writesData,
+	RunE:        trashRestore,
+}
+
+var trashPurgeCmd = &cobra.Command{
+	Use:         "purge [DB]",
+	Short:       "Empty a db's trash for good.",
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: writesData,
+	RunE:        trashPurge,
+}
+
+func trashList(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	dbName, err := trashDB(store, args)
+	if err != nil {
+		return err
+	}
+	tw := table.NewWriter()
+	tw.SetOutputMirror(cmd.OutOrStdout())
+	tw.SetStyle(table.StyleLight)
+	tw.AppendHeader(table.Row{"Key", "Deleted", "Purged in", "TTL left"})
+	trans := TransactionArgs{
+		key:      "@" + dbName,
+		readonly: true,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			return tx.Iterate(IterOptions{Prefix: []byte(trashPrefix), Values: true}, func(e Entry) error {
+				rec, err := decodeTrash(e)
+				if err != nil {
+					return err
+				}
+				key := string(rec.Key)
+				if rec.Meta&metaSecret != 0 {
+					key += " (secret)"
+				}
+				ttl := "never"
+				if rec.TTL > 0 {
+					ttl = (time.Duration(rec.TTL) * time.Second).String()
+				}
+				deleted := time.Unix(rec.DeletedAt, 0).UTC().Format(time.RFC3339)
+				tw.AppendRow(table.Row{key, deleted, formatExpiry(e.ExpiresAt), ttl})
+				return nil
+			})
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	tw.Render()
+	return nil
+}
+
+func trashRestore(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+	trans := TransactionArgs{
+		key:      args[0],
+		readonly: false,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			e, err := tx.Get(trashKey(k))
+			if errors.Is(err, errKeyNotFound) {
+				return fmt.Errorf("%q is not in the trash", args[0])
+			}
+			if err != nil {
+				return err
+			}
+			rec, err := decodeTrash(e)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Get(k); err == nil && !force {
+				return fmt.Errorf("%q has been set again since it was deleted; pass --force to overwrite it", args[0])
+			} else if err != nil && !errors.Is(err, errKeyNotFound) {
+				return err
+			}
+			restored := Entry{Key: k, Value: rec.Value, Meta: rec.Meta}
+			if rec.TTL > 0 {
+				restored.ExpiresAt = uint64(time.Now().Unix() + rec.TTL)
+			}
+			if err := tx.Set(restored); err != nil {
+				return err
+			}
+			return tx.Delete(trashKey(k))
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Restored %q\n", args[0])
+	return nil
+}
+
+func trashPurge(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	dbName, err := trashDB(store, args)
+	if err != nil {
+		return err
+	}
+	var purged int
+	trans := TransactionArgs{
+		key:      "@" + dbName,
+		readonly: false,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			var keys [][]byte
+			err := tx.Iterate(IterOptions{Prefix: []byte(trashPrefix)}, func(e Entry) error {
+				keys = append(keys, e.Key)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if err := tx.Delete(key); err != nil {
+					return err
+				}
+			}
+			purged = len(keys)
+			return nil
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Purged %d keys from the trash of @%s\n", purged, dbName)
+	return nil
+}
+
+// trashDB resolves the optional DB argument of the trash commands.
+func trashDB(store *Store, args []string) (string, error) {
+	if len(args) == 0 {
+		return defaultDB(), nil
+	}
+	dbName, err := store.parseDB(args[0], false)
+	if err != nil {
+		return "", err
+	}
+	if _, err := store.FindStore(dbName); err != nil {
+		var notFound errNotFound
+		if errors.As(err, &notFound) {
+			return "", fmt.Errorf("%q does not exist, %s", args[0], err.Error())
+		}
+		return "", err
+	}
+	return dbName, nil
+}
+
+func decodeTrash(e Entry) (trashRecord, error) {
+	var rec trashRecord
+	if err := json.Unmarshal(e.Value, &rec); err != nil {
+		return rec, fmt.Errorf("corrupt trash entry %q: %w", e.Key[len(trashPrefix):], err)
+	}
+	return rec, nil
+}
+
+func init() {
+	trashRestoreCmd.Flags().BoolP("force", "f", false, "overwrite the key if it has been set again")
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd, trashPurgeCmd)
+	rootCmd.AddCommand(trashCmd)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo [N] [DB]",
+	Short: "Undo the last N sets and deletes in a db.",
+	Long: `Undo the last N sets and deletes in a db, newest first. N defaults to 1.
+
+Every set and delete, from any pda command or server, is written to a
+journal kept in the db itself, holding the last journal.size writes.
+Undoing a delete also takes the key back out of the trash. Restores are
+not journaled. --list shows the journal instead of undoing anything.`,
+	Args:        cobra.MaximumNArgs(2),
+	Annotations: writesData,
+	RunE:        undo,
+}
+
+func undo(cmd *cobra.Command, args []string) error {
+	store := &Store{}
+	n := 1
+	dbName := defaultDB()
+	for _, arg := range args {
+		if v, err := strconv.Atoi(arg); err == nil {
+			if v < 1 {
+				return fmt.Errorf("N must be at least 1")
+			}
+			n = v
+			continue
+		}
+		name, err := store.parseDB(arg, false)
+		if err != nil {
+			return err
+		}
+		if _, err := store.FindStore(name); err != nil {
+			var notFound errNotFound
+			if errors.As(err, &notFound) {
+				return fmt.Errorf("%q does not exist, %s", arg, err.Error())
+			}
+			return err
+		}
+		dbName = name
+	}
+	list, err := cmd.Flags().GetBool("list")
+	if err != nil {
+		return err
+	}
+	if list {
+		return listJournal(cmd, store, dbName)
+	}
+
+	var undone []journalRecord
+	trans := TransactionArgs{
+		key:       "@" + dbName,
+		readonly:  false,
+		sync:      false,
+		noJournal: true,
+		transact: func(tx Tx, k []byte) error {
+			undone = nil
+			seqs, records, err := readJournal(tx)
+			if err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				return fmt.Errorf("nothing to undo in @%s", dbName)
+			}
+			for i := len(records) - 1; i >= 0 && len(undone) < n; i-- {
+				rec := records[i]
+				if rec.Prev == nil {
+					err = tx.Delete(rec.Key)
+				} else {
+					prev := *rec.Prev
+					prev.Version = 0
+					err = tx.Set(prev)
+				}
+				if err != nil {
+					return err
+				}
+				if rec.Op == "del" {
+					if err := tx.Delete(trashKey(rec.Key)); err != nil {
+						return err
+					}
+				}
+				if err := tx.Delete(journalKey(seqs[i])); err != nil {
+					return err
+				}
+				undone = append(undone, rec)
+			}
+			// The next write finds the new end of the journal itself.
+			return tx.Delete([]byte(journalHead))
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	for _, rec := range undone {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Undid %s of %q in @%s\n", rec.Op, rec.Key, dbName)
+	}
+	if len(undone) < n {
+		fmt.Fprintf(cmd.ErrOrStderr(), "The journal of @%s had only %d entries\n", dbName, len(undone))
+	}
+	return nil
+}
+
+func listJournal(cmd *cobra.Command, store *Store, dbName string) error {
+	tw := table.NewWriter()
+	tw.SetOutputMirror(cmd.OutOrStdout())
+	tw.SetStyle(table.StyleLight)
+	tw.AppendHeader(table.Row{"#", "Time", "Op", "Key", "Previously"})
+	trans := TransactionArgs{
+		key:      "@" + dbName,
+		readonly: true,
+		sync:     false,
+		transact: func(tx Tx, k []byte) error {
+			_, records, err := readJournal(tx)
+			if err != nil {
+				return err
+			}
+			for i := len(records) - 1; i >= 0; i-- {
+				rec := records[i]
+				previously := "(absent)"
+				if rec.Prev != nil {
+					previously = "set"
+					if rec.Prev.Meta&metaSecret != 0 {
+						previously = "set, secret"
+					}
+				}
+				when := time.Unix(rec.Time, 0).UTC().Format(time.RFC3339)
+				tw.AppendRow(table.Row{len(records) - i, when, rec.Op, string(rec.Key), previously})
+			}
+			return nil
+		},
+	}
+	if err := store.Transaction(trans); err != nil {
+		return err
+	}
+	tw.Render()
+	return nil
+}
+
+func init() {
+	undoCmd.Flags().BoolP("list", "l", false, "list the journal, newest first, instead of undoing")
+	rootCmd.AddCommand(undoCmd)
+}
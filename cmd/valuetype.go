@@ -0,0 +1,270 @@
+/*
+Copyright © 2025 Lewis Wynne <lew@ily.rs>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// metaType holds the type of a plain value in the high bits of its meta.
+// Zero leaves the value untyped, to hold anything.
+const metaType byte = 0xf0
+
+// valueType is the type a plain value was set with, which it is checked
+// against whenever it changes and which decides how it sorts and compares.
+type valueType byte
+
+const (
+	typeNone valueType = iota
+	typeString
+	typeInt
+	typeFloat
+	typeBool
+	typeJSON
+	typeDuration
+	typeTimestamp
+	typeBytes
+)
+
+var valueTypeNames = [...]string{"untyped", "string", "int", "float", "bool", "json", "duration", "timestamp", "bytes"}
+
+func parseValueType(name string) (valueType, error) {
+	for i, n := range valueTypeNames {
+		if i != int(typeNone) && n == name {
+			return valueType(i), nil
+		}
+	}
+	return typeNone, fmt.Errorf("unknown type %q; use %s", name, strings.Join(valueTypeNames[1:], ", "))
+}
+
+func valueTypeOf(meta byte) valueType {
+	return valueType(meta & metaType >> 4)
+}
+
+func (t valueType) meta() byte {
+	return byte(t) << 4
+}
+
+func (t valueType) String() string {
+	if int(t) < len(valueTypeNames) {
+		return valueTypeNames[t]
+	}
+	return "unknown"
+}
+
+// article is "an" or "a", to go before the type's name.
+func (t valueType) article() string {
+	if t == typeInt || t == typeNone {
+		return "an"
+	}
+	return "a"
+}
+
+// validate returns an error unless v is a valid value of type t.
+// Timestamps are RFC 3339 and durations are as time.ParseDuration reads
+// them.
+func (t valueType) validate(v []byte) error {
+	var err error
+	switch t {
+	case typeString:
+		if !utf8.Valid(v) {
+			err = errors.New("not valid UTF-8")
+		}
+	case typeJSON:
+		if !json.Valid(v) {
+			err = errors.New("not valid JSON")
+		}
+	case typeInt, typeFloat, typeBool, typeDuration, typeTimestamp:
+		_, err = t.parse(v)
+	}
+	if err != nil {
+		return fmt.Errorf("%q is not %s %s", v, t.article(), t)
+	}
+	return nil
+}
+
+// parse returns v as a Go value of type t, for comparing.
+func (t valueType) parse(v []byte) (any, error) {
+	s := string(v)
+	switch t {
+	case typeInt:
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, strconv.ErrSyntax
+		}
+		return new(big.Rat).SetInt(n), nil
+	case typeFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case typeBool:
+		return strconv.ParseBool(s)
+	case typeDuration:
+		return time.ParseDuration(s)
+	case typeTimestamp:
+		return time.Parse(time.RFC3339Nano, s)
+	default:
+		return v, nil
+	}
+}
+
+// compareValues orders a and b as values of type t: numbers, durations
+// and timestamps by size, false before true, and anything else, or a
+// value that does not parse, by its bytes.
+func compareValues(t valueType, a, b []byte) int {
+	x, errA := t.parse(a)
+	y, errB := t.parse(b)
+	if errA != nil || errB != nil {
+		return bytes.Compare(a, b)
+	}
+	switch x := x.(type) {
+	case *big.Rat:
+		return x.Cmp(y.(*big.Rat))
+	case float64:
+		return compareOrdered(x, y.(float64))
+	case time.Duration:
+		return compareOrdered(x, y.(time.Duration))
+	case time.Time:
+		return x.Compare(y.(time.Time))
+	case bool:
+		return compareBool(x, y.(bool))
+	default:
+		return bytes.Compare(a, b)
+	}
+}
+
+// compareBool orders false before true.
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func compareOrdered[T float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// typedMeta returns the meta bits of a plain value about to be written to
+// key: t if given, and otherwise the type key already has. v must be a
+// valid value of that type.
+func typedMeta(tx Tx, key, v []byte, t valueType, given bool) (byte, error) {
+	if !given {
+		cur, err := tx.Get(key)
+		if err != nil && !errors.Is(err, errKeyNotFound) {
+			return 0, err
+		}
+		if err == nil && cur.Meta&metaKind == 0 {
+			t = valueTypeOf(cur.Meta)
+		}
+	}
+	if err := t.validate(v); err != nil {
+		if !given {
+			return 0, fmt.Errorf("%q is typed %s: %w", key, t, err)
+		}
+		return 0, err
+	}
+	return t.meta(), nil
+}
+
+// typeName is what list shows in its type column: the kind of a
+// collection, or the type of a plain value.
+func typeName(meta byte) string {
+	if meta&metaKind != 0 {
+		return kindName(meta)
+	}
+	return valueTypeOf(meta).String()
+}
+
+// whereClause is a list --where filter such as "int > 10": values of a type
+// compared with an operand, or with no operator, all values of a type.
+type whereClause struct {
+	t       valueType
+	op      string
+	operand []byte
+}
+
+var whereSyntax = regexp.MustCompile(`^\s*(\w+)\s*(?:(==|=|!=|<=|>=|<|>)\s*(.*?))?\s*$`)
+
+func parseWhere(s string) (whereClause, error) {
+	m := whereSyntax.FindStringSubmatch(s)
+	if m == nil {
+		return whereClause{}, fmt.Errorf("bad --where %q; use TYPE, or TYPE OP VALUE with OP one of = != < <= > >=", s)
+	}
+	t, err := parseValueType(m[1])
+	if err != nil {
+		return whereClause{}, err
+	}
+	w := whereClause{t: t, op: m[2], operand: []byte(m[3])}
+	if w.op != "" {
+		if err := t.validate(w.operand); err != nil {
+			return whereClause{}, fmt.Errorf("bad --where %q: %w", s, err)
+		}
+	}
+	return w, nil
+}
+
+// match reports whether the plain value v, with the given meta, passes w.
+func (w whereClause) match(meta byte, v []byte) bool {
+	if meta&metaKind != 0 || valueTypeOf(meta) != w.t {
+		return false
+	}
+	c := compareValues(w.t, v, w.operand)
+	switch w.op {
+	case "":
+		return true
+	case "=", "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
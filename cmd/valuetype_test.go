@@ -0,0 +1,62 @@
+package cmd
+
+import "testing"
+
+func TestWhereMatch(t *testing.T) {
+	tests := []struct {
+		where string
+		t     valueType
+		value string
+		want  bool
+	}{
+		{"int > 10", typeInt, "11", true},
+		{"int > 10", typeInt, "9", false},
+		{"int > 10", typeInt, "100", true},
+		{"int >= 10", typeInt, "10", true},
+		{"int < -1", typeInt, "-2", true},
+		{"int = 99999999999999999999", typeInt, "99999999999999999999", true},
+		{"int == 7", typeInt, "7", true},
+		{"int != 7", typeInt, "7", false},
+		{"float <= 1.5", typeFloat, "1.25", true},
+		{"float > 1e3", typeFloat, "999", false},
+		{"bool = true", typeBool, "1", true},
+		{"bool < true", typeBool, "false", true},
+		{"duration > 1m", typeDuration, "90s", true},
+		{"duration > 1m", typeDuration, "59s", false},
+		{"timestamp < 2025-01-01T00:00:00Z", typeTimestamp, "2024-12-31T23:59:59Z", true},
+		{"timestamp = 2025-01-01T01:00:00+01:00", typeTimestamp, "2025-01-01T00:00:00Z", true},
+		{"string > b", typeString, "c", true},
+		{"string > b", typeString, "a", false},
+		{"int", typeInt, "5", true},
+		{"int", typeFloat, "5", false},
+		{"int > 1", typeNone, "5", false},
+		{"  int>1  ", typeInt, "2", true},
+	}
+	for _, tt := range tests {
+		w, err := parseWhere(tt.where)
+		if err != nil {
+			t.Fatalf("parseWhere(%q): %v", tt.where, err)
+		}
+		if got := w.match(tt.t.meta(), []byte(tt.value)); got != tt.want {
+			t.Errorf("--where %q on %s %q = %v, want %v", tt.where, tt.t, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWhereMatchSkipsCollections(t *testing.T) {
+	w, err := parseWhere("string")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.match(kindList|typeString.meta(), nil) {
+		t.Error("--where string matched a list")
+	}
+}
+
+func TestParseWhereErrors(t *testing.T) {
+	for _, s := range []string{"", "> 1", "nope > 1", "int > x", "duration < soon", "int ~ 1"} {
+		if _, err := parseWhere(s); err == nil {
+			t.Errorf("parseWhere(%q) succeeded", s)
+		}
+	}
+}